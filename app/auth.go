@@ -0,0 +1,199 @@
+package webserver
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role determines which API calls a client is allowed to execute. Each
+// role includes the permissions of the roles below.
+type Role int
+
+const (
+	// RoleNone is assigned to unauthenticated clients.
+	RoleNone Role = iota
+	// RoleViewer allows to read the status of routes, services, etc.
+	RoleViewer
+	// RoleOperator additionally allows to switch routes, reset the 4G
+	// modem and to start / restart services.
+	RoleOperator
+	// RoleAdmin additionally allows to stop services.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleNone:     "none",
+	RoleViewer:   "viewer",
+	RoleOperator: "operator",
+	RoleAdmin:    "admin",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// ParseRole returns the Role with the given name.
+func ParseRole(name string) (Role, error) {
+	name = strings.ToLower(name)
+	for r, n := range roleNames {
+		if n == name && r != RoleNone {
+			return r, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %s", name)
+}
+
+// Authenticator verifies the credentials of a request. If the credentials
+// are valid, the name of the client and its role are returned.
+type Authenticator interface {
+	Authenticate(req *http.Request) (name string, role Role, ok bool)
+}
+
+type identity struct {
+	name   string
+	secret string
+	role   Role
+}
+
+// TokenAuth authenticates clients through static API tokens which are sent
+// in the "Authorization: Bearer <token>" header.
+type TokenAuth struct {
+	tokens []identity
+}
+
+// NewTokenAuth returns an empty TokenAuth.
+func NewTokenAuth() *TokenAuth {
+	return &TokenAuth{}
+}
+
+// Add registers an API token with the given role. The name is only used
+// for logging.
+func (a *TokenAuth) Add(name, token string, role Role) {
+	a.tokens = append(a.tokens, identity{name, token, role})
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *TokenAuth) Authenticate(req *http.Request) (string, Role, bool) {
+	h := req.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return "", RoleNone, false
+	}
+	token := strings.TrimPrefix(h, "Bearer ")
+
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.secret), []byte(token)) == 1 {
+			return t.name, t.role, true
+		}
+	}
+	return "", RoleNone, false
+}
+
+// BasicAuth authenticates clients through HTTP basic authentication. The
+// passwords are stored as bcrypt hashes.
+type BasicAuth struct {
+	users map[string]identity
+}
+
+// NewBasicAuth returns an empty BasicAuth.
+func NewBasicAuth() *BasicAuth {
+	return &BasicAuth{
+		users: make(map[string]identity),
+	}
+}
+
+// Add registers a user with the bcrypt hash of its password and a role.
+func (a *BasicAuth) Add(user, hash string, role Role) {
+	a.users[user] = identity{user, hash, role}
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *BasicAuth) Authenticate(req *http.Request) (string, Role, bool) {
+	user, password, ok := req.BasicAuth()
+	if !ok {
+		return "", RoleNone, false
+	}
+	u, ok := a.users[user]
+	if !ok {
+		return "", RoleNone, false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.secret), []byte(password)); err != nil {
+		return "", RoleNone, false
+	}
+	return u.name, u.role, true
+}
+
+// ClientCertAuth authenticates clients through TLS client certificates
+// (mTLS). The certificates must have been verified against the client CA
+// of the server. The role is determined by the certificate's common name.
+type ClientCertAuth struct {
+	clients map[string]Role
+}
+
+// NewClientCertAuth returns an empty ClientCertAuth.
+func NewClientCertAuth() *ClientCertAuth {
+	return &ClientCertAuth{
+		clients: make(map[string]Role),
+	}
+}
+
+// Add assigns a role to the client certificate with the given common name.
+// The common name is case insensitive.
+func (a *ClientCertAuth) Add(commonName string, role Role) {
+	a.clients[strings.ToLower(commonName)] = role
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *ClientCertAuth) Authenticate(req *http.Request) (string, Role, bool) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return "", RoleNone, false
+	}
+	cn := req.TLS.VerifiedChains[0][0].Subject.CommonName
+	role, ok := a.clients[strings.ToLower(cn)]
+	if !ok {
+		return "", RoleNone, false
+	}
+	return cn, role, true
+}
+
+// authorize is an http middleware which only calls the provided handler
+// if the client is authenticated by one of the configured authenticators
+// and has at least the required role. If no authenticators are configured,
+// all requests are allowed.
+func (s *Server) authorize(required Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+
+		if len(s.authenticators) == 0 {
+			next(w, req)
+			return
+		}
+
+		for _, a := range s.authenticators {
+			name, role, ok := a.Authenticate(req)
+			if !ok {
+				continue
+			}
+			if role < required {
+				log.Printf("%s (%s) not authorized for %s\n", name, role, req.URL.Path)
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(fmt.Sprintf("role %s required", required)))
+				return
+			}
+			next(w, req)
+			return
+		}
+
+		for _, a := range s.authenticators {
+			if _, ok := a.(*BasicAuth); ok {
+				w.Header().Set("WWW-Authenticate", `Basic realm="infractl"`)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("authentication required"))
+	}
+}
@@ -0,0 +1,151 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dh1tw/infractl/backup"
+)
+
+// handleBackups returns the stored configuration backups of the router
+func (s *Server) handleBackups(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if rt.Backups == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("backups not enabled"))
+		return
+	}
+
+	snaps, err := rt.Backups.List()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(snaps); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode backups to json"))
+	}
+}
+
+// backupDiff is the reply of the backup diff handlers
+type backupDiff struct {
+	Backup  backup.Snapshot `json:"backup"`
+	Against string          `json:"against"`
+	Changed bool            `json:"changed"`
+	Diff    string          `json:"diff"`
+}
+
+// handleBackupDiff returns the unified diff between the two latest stored
+// backups of the router. The router itself is not accessed.
+func (s *Server) handleBackupDiff(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if rt.Backups == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("backups not enabled"))
+		return
+	}
+
+	snaps, err := rt.Backups.List()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if len(snaps) < 2 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("at least two backups are required for a diff"))
+		return
+	}
+	prev, latest := snaps[len(snaps)-2], snaps[len(snaps)-1]
+
+	exports := make([]string, 2)
+	for i, snap := range []backup.Snapshot{prev, latest} {
+		exports[i], err = snap.Export()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	diff := backup.Diff(exports[0], exports[1], prev.Name, latest.Name)
+
+	res := backupDiff{prev, latest.Name, len(diff) > 0, diff}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode diff to json"))
+	}
+}
+
+// handleBackupDiffLive returns the unified diff between the latest backup
+// and the live configuration of the router. Since the export creates and
+// deletes a file on the router, it has to be requested explicitly (POST).
+func (s *Server) handleBackupDiffLive(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if rt.Backups == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("backups not enabled"))
+		return
+	}
+
+	latest, err := rt.Backups.Latest()
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	saved, err := latest.Export()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	live, err := rt.Microtik.Export()
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	diff := backup.Diff(saved, live, latest.Name, "live")
+
+	res := backupDiff{latest, "live", len(diff) > 0, diff}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode diff to json"))
+	}
+}
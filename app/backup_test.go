@@ -0,0 +1,94 @@
+package webserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dh1tw/infractl/backup"
+	"github.com/dh1tw/infractl/microtik/routerostest"
+)
+
+func TestHandleBackupDiff(t *testing.T) {
+
+	s, srv := newTestServer(t)
+
+	dir, err := ioutil.TempDir("", "infractl-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := backup.NewStore(dir, 0)
+	s.routers["default"].Backups = store
+
+	// the live configuration is exported to a file on the router
+	live := "/ip dns\nset servers=1.1.1.1\n"
+	file := ""
+	srv.Handle("/export", func(cmd routerostest.Command) ([]map[string]string, error) {
+		file = cmd.Args["file"] + ".rsc"
+		return nil, nil
+	})
+	srv.Handle("/file/print", func(routerostest.Command) ([]map[string]string, error) {
+		return []map[string]string{{".id": "*1", "name": file, "size": strconv.Itoa(len(live))}}, nil
+	})
+	srv.Handle("/file/read", func(routerostest.Command) ([]map[string]string, error) {
+		return []map[string]string{{"data": live}}, nil
+	})
+	srv.Handle("/file/remove", func(routerostest.Command) ([]map[string]string, error) {
+		return nil, nil
+	})
+
+	decode := func(body string) backupDiff {
+		t.Helper()
+		res := backupDiff{}
+		if err := json.NewDecoder(strings.NewReader(body)).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if rec := serve(s, "GET", "/api/v1.0/backup/diff"); rec.Code != http.StatusNotFound {
+		t.Errorf("diff without backups: got status %d", rec.Code)
+	}
+
+	for _, export := range []string{"/ip dns\nset servers=8.8.8.8\n", live} {
+		if _, err := store.Save(export, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snaps, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GET compares the two latest backups without accessing the router
+	rec := serve(s, "GET", "/api/v1.0/backup/diff")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+	res := decode(rec.Body.String())
+	if res.Backup.Name != snaps[0].Name || res.Against != snaps[1].Name || !res.Changed ||
+		!strings.Contains(res.Diff, "-set servers=8.8.8.8\n+set servers=1.1.1.1\n") {
+		t.Errorf("unexpected diff %+v", res)
+	}
+	for _, cmd := range srv.Commands() {
+		if cmd.Path == "/export" {
+			t.Fatal("GET exported the live configuration")
+		}
+	}
+
+	// POST compares the latest backup with the live configuration
+	rec = serve(s, "POST", "/api/v1.0/backup/diff")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+	res = decode(rec.Body.String())
+	if res.Backup.Name != snaps[1].Name || res.Against != "live" || res.Changed || len(res.Diff) > 0 {
+		t.Errorf("unexpected diff %+v", res)
+	}
+}
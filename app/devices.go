@@ -0,0 +1,42 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dh1tw/infractl/devices"
+)
+
+// handleDevices returns the devices in the network of the router, detected
+// through its DHCP leases and ARP table. Required devices which are offline
+// are flagged as missing.
+func (s *Server) handleDevices(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	leases, err := rt.Microtik.DHCPLeases()
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	arp, err := rt.Microtik.ARP()
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(devices.Merge(leases, arp, rt.Devices)); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode devices to json"))
+	}
+}
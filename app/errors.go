@@ -0,0 +1,39 @@
+package webserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dh1tw/infractl/microtik"
+)
+
+// routerErrorStatus returns the HTTP status code matching an error
+// returned by a microtik router: 404 for unknown routes and NAT rules,
+// routes and NAT rules which can't be found on the router and addresses
+// which aren't in an address list, 400 for invalid addresses and route
+// modifications, 403 for address lists which may not be modified, 401 if
+// the router rejects the credentials, 409 if the routes are locked by a
+// transaction, 502 if the router rejects a command and 504 if it is
+// unreachable or doesn't reply in time. All other errors result in 500.
+func routerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, microtik.ErrUnknownRoute), errors.Is(err, microtik.ErrRouteNotFound),
+		errors.Is(err, microtik.ErrUnknownNatRule), errors.Is(err, microtik.ErrNatRuleNotFound),
+		errors.Is(err, microtik.ErrAddressNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, microtik.ErrListNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, microtik.ErrInvalidAddress), errors.Is(err, microtik.ErrInvalidRouteOp):
+		return http.StatusBadRequest
+	case errors.Is(err, microtik.ErrAuth):
+		return http.StatusUnauthorized
+	case errors.Is(err, microtik.ErrTransactionRunning):
+		return http.StatusConflict
+	case errors.Is(err, microtik.ErrRouterTrap):
+		return http.StatusBadGateway
+	case errors.Is(err, microtik.ErrUnreachable):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,38 @@
+package webserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dh1tw/infractl/microtik"
+)
+
+func TestRouterErrorStatus(t *testing.T) {
+
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w adsl", microtik.ErrUnknownRoute), http.StatusNotFound},
+		{fmt.Errorf("%w adsl: no route matches", microtik.ErrRouteNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: invalid user name or password (6)", microtik.ErrAuth), http.StatusUnauthorized},
+		{&microtik.TrapError{Command: "/ip/route/set", Message: "failure"}, http.StatusBadGateway},
+		{fmt.Errorf("%w: i/o timeout", microtik.ErrUnreachable), http.StatusGatewayTimeout},
+		{fmt.Errorf("unable to modify route adsl: %w", microtik.ErrTransactionRunning), http.StatusConflict},
+		{fmt.Errorf("%w: blocked", microtik.ErrListNotAllowed), http.StatusForbidden},
+		{fmt.Errorf("%w 10.0.0.300", microtik.ErrInvalidAddress), http.StatusBadRequest},
+		{fmt.Errorf("%w: 10.0.0.1 in address list blocked", microtik.ErrAddressNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w ssh", microtik.ErrUnknownNatRule), http.StatusNotFound},
+		{fmt.Errorf("%w: no rule with comment \"ssh\"", microtik.ErrNatRuleNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: route adsl: modifying attribute gateway is not allowed", microtik.ErrInvalidRouteOp), http.StatusBadRequest},
+		{errors.New("unable to determine the id of route adsl"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		if got := routerErrorStatus(tc.err); got != tc.status {
+			t.Errorf("%v: got %d, expected %d", tc.err, got, tc.status)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package webserver
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+)
+
+// Event is a change on one of the microtik routers, as published on the
+// event bus. The IDs are increasing, so that clients can request the
+// events which they haven't received yet.
+type Event struct {
+	ID     uint64 `json:"id"`
+	Router string `json:"router"`
+	microtik.Event
+}
+
+// eventBus distributes the events of the routers to the waiting clients
+// and keeps the latest events in a history.
+type eventBus struct {
+	sync.Mutex
+	lastID  uint64
+	history []Event
+	size    int
+	// closed and replaced on every published event
+	notify chan struct{}
+}
+
+func newEventBus(size int) *eventBus {
+	return &eventBus{
+		history: []Event{},
+		size:    size,
+		notify:  make(chan struct{}),
+	}
+}
+
+func (b *eventBus) publish(router string, e microtik.Event) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.lastID++
+	b.history = append(b.history, Event{ID: b.lastID, Router: router, Event: e})
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+// since returns the events with an ID greater than id, the ID of the
+// latest event and a channel which is closed when the next event is
+// published. If id is unknown (e.g. after a restart of the server), the
+// whole history is returned.
+func (b *eventBus) since(id uint64) ([]Event, uint64, <-chan struct{}) {
+	b.Lock()
+	defer b.Unlock()
+
+	if id > b.lastID {
+		id = 0
+	}
+
+	events := []Event{}
+	for _, e := range b.history {
+		if e.ID > id {
+			events = append(events, e)
+		}
+	}
+	return events, b.lastID, b.notify
+}
+
+// startEvents listens for the changes on all routers and publishes them
+// on the event bus.
+func (s *Server) startEvents() {
+	for name, rt := range s.routers {
+		name := name
+		rt.Microtik.Listen(func(e microtik.Event) {
+			log.Printf("router %s: %s\n", name, e.Message)
+			s.events.publish(name, e)
+		})
+	}
+}
+
+// handleEvents returns the events of the routers with an ID greater than
+// ?since=. If there are none, the request is held open until the next
+// event is published or the ?timeout= (default: 25s, max: 50s) expires
+// (long polling). Without ?since= the whole history is returned.
+func (s *Server) handleEvents(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if s.events == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("events not enabled"))
+		return
+	}
+
+	q := req.URL.Query()
+
+	since := uint64(0)
+	if v := q.Get("since"); len(v) > 0 {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid since " + v))
+			return
+		}
+		since = id
+	}
+
+	timeout := time.Second * 25
+	if v := q.Get("timeout"); len(v) > 0 {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 || d > time.Second*50 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid timeout " + v + " (max 50s)"))
+			return
+		}
+		timeout = d
+	}
+
+	events, lastID, notify := s.events.since(since)
+
+	if len(events) == 0 && len(q.Get("since")) > 0 {
+		select {
+		case <-notify:
+			events, lastID, _ = s.events.since(since)
+		case <-time.After(timeout):
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	res := struct {
+		Events []Event `json:"events"`
+		LastID uint64  `json:"last_id"`
+	}{events, lastID}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode events to json"))
+	}
+}
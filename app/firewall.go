@@ -0,0 +1,170 @@
+package webserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// handleAddressList returns the entries of a firewall address list
+func (s *Server) handleAddressList(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	entries, err := rt.Microtik.AddressListEntries(mux.Vars(req)["list"])
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode address list to json"))
+	}
+}
+
+// addressListEntry is the body of the requests for adding and removing
+// entries of an address list. The timeout is a duration like "24h".
+type addressListEntry struct {
+	Address string `json:"address"`
+	Timeout string `json:"timeout"`
+	Comment string `json:"comment"`
+}
+
+// handleAddressListAdd adds an ip address or network to a firewall
+// address list, e.g. {"address": "203.0.113.7", "timeout": "24h"}
+func (s *Server) handleAddressListAdd(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	entry := addressListEntry{}
+	if err := json.NewDecoder(req.Body).Decode(&entry); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("unable to decode address list entry: %v", err)))
+		return
+	}
+
+	var timeout time.Duration
+	if len(entry.Timeout) > 0 {
+		timeout, err = time.ParseDuration(entry.Timeout)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("invalid timeout %s", entry.Timeout)))
+			return
+		}
+	}
+
+	err = rt.Microtik.AddToAddressList(mux.Vars(req)["list"], entry.Address, timeout, entry.Comment)
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+}
+
+// handleAddressListRemove removes an ip address or network from a firewall
+// address list, e.g. {"address": "203.0.113.7"}
+func (s *Server) handleAddressListRemove(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	entry := addressListEntry{}
+	if err := json.NewDecoder(req.Body).Decode(&entry); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("unable to decode address list entry: %v", err)))
+		return
+	}
+
+	err = rt.Microtik.RemoveFromAddressList(mux.Vars(req)["list"], entry.Address)
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+}
+
+// handleNatRules returns the registered NAT rules
+func (s *Server) handleNatRules(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	rules, err := rt.Microtik.NatRules()
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(rules); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode nat rules to json"))
+	}
+}
+
+func (s *Server) handleNatRuleEnable(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := rt.Microtik.EnableNatRule(mux.Vars(req)["rule"]); err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+}
+
+func (s *Server) handleNatRuleDisable(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := rt.Microtik.DisableNatRule(mux.Vars(req)["rule"]); err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+}
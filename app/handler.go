@@ -3,7 +3,9 @@ package webserver
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,7 +22,6 @@ import (
 func (s *Server) handlePing(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	vars := mux.Vars(req)
 	host := strings.ToLower(vars["host"])
@@ -44,45 +45,117 @@ func (s *Server) handlePing(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-// handleReset4G the 4G modem attached to the Microtik Router (needs to be supported
-// the routerboard)
+// handlePingResults returns the cached results of the background ping job
+func (s *Server) handlePingResults(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err := json.NewEncoder(w).Encode(s.pingStatus()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode ping data to json"))
+	}
+}
+
+// handleReset4G resets the 4G modem attached to the Microtik Router (needs to
+// be supported by the routerboard) according to the configured policy. The
+// reset is executed in the background, the returned job can be polled
+// through handleReset4GStatus.
 func (s *Server) handleReset4G(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	s.Lock()
-	defer s.Unlock()
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
 
-	if s.microtik == nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("no microtik instance configured"))
+	if rt.Reset4G == nil {
+		if err := rt.Microtik.Reset4G(); err != nil {
+			w.WriteHeader(routerErrorStatus(err))
+			w.Write([]byte(err.Error()))
+		}
 		return
 	}
 
-	// before we can reset the 4G modem, we must make sure that the ADSL route
-	// is active. Otherwise, when the 4G route would become unavailable after the reset
-	// and no other route is available, microtik generates a new dynamical route which
-	// messes up the configuration.
-	err := s.microtik.SetRoute("adsl", "disabled=false")
+	job, err := rt.Reset4G.Start()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusConflict)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	err = s.microtik.Reset4G()
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Println("unable to encode reset job to json:", err)
+	}
+}
+
+// handleReset4GStatus returns the progress of the current (or last) reset
+// of the 4G modem.
+func (s *Server) handleReset4GStatus(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
+	rt, err := s.lookupRouter(req)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if rt.Reset4G == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("no reset policy configured"))
+		return
+	}
+
+	job := rt.Reset4G.Status()
+	if job.ID == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("no reset executed yet"))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode reset job to json"))
 	}
 }
 
-//retrieve the requested status from a ZTE MF823 4G modem
+//retrieve the requested status from a ZTE MF823 4G modem or from the LTE
+//interface of the microtik router. The source can be overridden with
+//?source=mf823|router
 func (s *Server) handleStatus4G(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	source := s.lteSource
+	if src := req.URL.Query().Get("source"); len(src) > 0 {
+		source = src
+	}
+
+	// the MF823 doesn't belong to a router, but it must not be reported
+	// for an unknown router either. Without a router name, it is also
+	// available if no router is configured.
+	rt, err := s.lookupRouter(req)
+	if err != nil && (source == "router" || len(mux.Vars(req)["router"]) > 0) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	switch source {
+	case "mf823":
+	case "router":
+		s.handleStatus4GRouter(w, rt)
+		return
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("invalid source %s (mf823, router)", source)))
+		return
+	}
 
 	s.Lock()
 	addr := s.mf823Address
@@ -110,10 +183,37 @@ func (s *Server) handleStatus4G(w http.ResponseWriter, req *http.Request) {
 	w.Write(j)
 }
 
+// handleStatus4GRouter retrieves the status of the LTE interface of the
+// microtik router
+func (s *Server) handleStatus4GRouter(w http.ResponseWriter, rt *Router) {
+
+	if len(rt.LTEInterface) == 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("no LTE interface configured"))
+		return
+	}
+
+	info, err := rt.Microtik.LTEInfo(rt.LTEInterface)
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	res := struct {
+		Source string `json:"source"`
+		microtik.LTEInfo
+	}{"router", info}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode LTE status to json"))
+	}
+}
+
 func (s *Server) handleServicesList(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	s.Lock()
 	myServices := s.services
@@ -145,7 +245,6 @@ func (s *Server) handleServicesList(w http.ResponseWriter, req *http.Request) {
 func (s *Server) handleServiceStart(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	s.Lock()
 	defer s.Unlock()
@@ -170,7 +269,6 @@ func (s *Server) handleServiceStart(w http.ResponseWriter, req *http.Request) {
 func (s *Server) handleServiceStop(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	s.Lock()
 	defer s.Unlock()
@@ -195,7 +293,6 @@ func (s *Server) handleServiceStop(w http.ResponseWriter, req *http.Request) {
 func (s *Server) handleServiceRestart(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	s.Lock()
 	defer s.Unlock()
@@ -220,23 +317,22 @@ func (s *Server) handleServiceRestart(w http.ResponseWriter, req *http.Request)
 func (s *Server) handleRoutes(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	s.Lock()
-	defer s.Unlock()
 
-	if s.microtik == nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("no microtik instance configured"))
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
 		return
 	}
 
 	results := make(map[string]microtik.RouteResult)
 
-	for _, route := range s.mtRoutes {
-		res, err := s.microtik.RouteStatus(route)
+	for _, route := range rt.Routes {
+		res, err := rt.Microtik.RouteStatus(route)
 		if err != nil {
+			w.WriteHeader(routerErrorStatus(err))
 			w.Write([]byte(err.Error()))
+			return
 		}
 		results[route] = res
 	}
@@ -250,27 +346,51 @@ func (s *Server) handleRoutes(w http.ResponseWriter, req *http.Request) {
 	w.Write(j)
 }
 
-func (s *Server) handleRoute(w http.ResponseWriter, req *http.Request) {
+// handleRoutesAll returns all routes of the microtik router, including the
+// unregistered and dynamic ones
+func (s *Server) handleRoutesAll(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	s.Lock()
-	defer s.Unlock()
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
 
-	if s.microtik == nil {
+	routes, err := rt.Microtik.Routes()
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(routes); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("no microtik instance configured"))
+		w.Write([]byte("unable to encode routes to json"))
+	}
+}
+
+func (s *Server) handleRoute(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
 		return
 	}
 
 	vars := mux.Vars(req)
 	rName := strings.ToLower(vars["route"])
 
-	res, err := s.microtik.RouteStatus(rName)
+	res, err := rt.Microtik.RouteStatus(rName)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(routerErrorStatus(err))
 		w.Write([]byte(err.Error()))
+		return
 	}
 
 	j, err := json.Marshal(res)
@@ -285,17 +405,20 @@ func (s *Server) handleRoute(w http.ResponseWriter, req *http.Request) {
 func (s *Server) handleRouteEnable(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	s.Lock()
-	defer s.Unlock()
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
 
 	vars := mux.Vars(req)
 	rName := strings.ToLower(vars["route"])
 
-	err := s.microtik.SetRoute(rName, "disabled=false")
+	err = rt.Microtik.UpdateRoute(rName, microtik.Enable())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(routerErrorStatus(err))
 		w.Write([]byte(err.Error()))
 		return
 	}
@@ -304,18 +427,312 @@ func (s *Server) handleRouteEnable(w http.ResponseWriter, req *http.Request) {
 func (s *Server) handleRouteDisable(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	s.Lock()
-	defer s.Unlock()
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
 
 	vars := mux.Vars(req)
 	rName := strings.ToLower(vars["route"])
 
-	err := s.microtik.SetRoute(rName, "disabled=true")
+	err = rt.Microtik.UpdateRoute(rName, microtik.Disable())
 	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+}
+
+// handleRouterHealth returns the system resources and the sensor readings
+// of the microtik router
+func (s *Server) handleRouterHealth(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	res, err := rt.Microtik.SystemResource()
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	health, err := rt.Microtik.Health()
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	status := struct {
+		Resource microtik.SystemResource `json:"resource"`
+		Health   microtik.Health         `json:"health"`
+	}{res, health}
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode router health to json"))
+	}
+}
+
+// routerPingOptions parses the options for pings and traceroutes sent by
+// the router from the query (?uplink=adsl, ?interface=, ?src_address=,
+// ?routing_table=, ?count=). An uplink is resolved to its interface.
+func routerPingOptions(rt *Router, req *http.Request) (microtik.PingOptions, error) {
+
+	q := req.URL.Query()
+
+	opts := microtik.PingOptions{
+		Interface:    q.Get("interface"),
+		SrcAddress:   q.Get("src_address"),
+		RoutingTable: q.Get("routing_table"),
+	}
+
+	if uplink := q.Get("uplink"); len(uplink) > 0 {
+		iface, ok := rt.Uplinks[strings.ToLower(uplink)]
+		if !ok {
+			return opts, fmt.Errorf("unknown uplink %s", uplink)
+		}
+		opts.Interface = iface
+	}
+
+	// the router replies after all pings have been sent (1 per second)
+	if c := q.Get("count"); len(c) > 0 {
+		count, err := strconv.Atoi(c)
+		if err != nil || count < 1 || count > 5 {
+			return opts, fmt.Errorf("invalid count %s (1-5)", c)
+		}
+		opts.Count = count
+	}
+
+	return opts, nil
+}
+
+// A traceroute probe to a hop which doesn't reply times out after 1s. The
+// amount of probes per hop and the amount of hops are limited, so that even
+// a traceroute without any replies (3*15s plus the router's timeout) ends
+// before the WriteTimeout of the webserver (60s).
+const (
+	maxTracerouteCount = 3
+	maxTracerouteHops  = 15
+)
+
+// routerTracerouteOptions parses the options for a traceroute sent by the
+// router. In addition to the ping options, the amount of hops can be
+// limited (?max_hops=).
+func routerTracerouteOptions(rt *Router, req *http.Request) (microtik.PingOptions, error) {
+
+	opts, err := routerPingOptions(rt, req)
+	if err != nil {
+		return opts, err
+	}
+
+	if opts.Count > maxTracerouteCount {
+		return opts, fmt.Errorf("invalid count %d (1-%d)", opts.Count, maxTracerouteCount)
+	}
+
+	opts.MaxHops = maxTracerouteHops
+	if h := req.URL.Query().Get("max_hops"); len(h) > 0 {
+		hops, err := strconv.Atoi(h)
+		if err != nil || hops < 1 || hops > maxTracerouteHops {
+			return opts, fmt.Errorf("invalid max_hops %s (1-%d)", h, maxTracerouteHops)
+		}
+		opts.MaxHops = hops
+	}
+
+	return opts, nil
+}
+
+// handleRouterPing pings a host from the microtik router, e.g. through the
+// interface of an uplink (?uplink=4g)
+func (s *Server) handleRouterPing(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	opts, err := routerPingOptions(rt, req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
 		return
 	}
+
+	res, err := rt.Microtik.Ping(mux.Vars(req)["host"], opts)
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode ping result to json"))
+	}
+}
+
+// handleRouterTraceroute determines the path from the microtik router to
+// a host, e.g. through the interface of an uplink (?uplink=4g). At most
+// 15 hops are traced (?max_hops=).
+func (s *Server) handleRouterTraceroute(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	opts, err := routerTracerouteOptions(rt, req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	hops, err := rt.Microtik.Traceroute(mux.Vars(req)["host"], opts)
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(hops); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode traceroute to json"))
+	}
+}
+
+// handleInterfaces returns the interfaces of the microtik router with their
+// state and counters
+func (s *Server) handleInterfaces(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	ifaces, err := rt.Microtik.Interfaces()
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(ifaces); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode interfaces to json"))
+	}
+}
+
+// handleTraffic returns the current throughput of the interfaces provided
+// in the query (?interface=ether1&interface=lte1), or else of the uplinks.
+// The results are indexed by the interface or the uplink name.
+func (s *Server) handleTraffic(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	// interface name -> result key
+	keys := make(map[string]string)
+
+	for _, iface := range req.URL.Query()["interface"] {
+		keys[iface] = iface
+	}
+
+	if len(keys) == 0 {
+		for uplink, iface := range rt.Uplinks {
+			keys[iface] = uplink
+		}
+	}
+
+	if len(keys) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("no interfaces provided and no uplinks configured"))
+		return
+	}
+
+	ifaces := make([]string, 0, len(keys))
+	for iface := range keys {
+		ifaces = append(ifaces, iface)
+	}
+
+	traffic, err := rt.Microtik.MonitorTraffic(ifaces...)
+	if err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	res := make(map[string]microtik.Traffic)
+	for _, t := range traffic {
+		res[keys[t.Interface]] = t
+	}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode traffic to json"))
+	}
+}
+
+// handleRouters returns the names of the microtik routers and the name of
+// the default router
+func (s *Server) handleRouters(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	res := struct {
+		Routers []string `json:"routers"`
+		Default string   `json:"default"`
+	}{
+		Routers: s.routerNames(),
+		Default: s.defaultRouter,
+	}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode routers to json"))
+	}
+}
+
+func (s *Server) handleFailover(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if s.failover == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("failover not enabled"))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(s.failover.Status()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode failover status to json"))
+	}
 }
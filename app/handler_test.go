@@ -0,0 +1,311 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/dh1tw/infractl/microtik/routerostest"
+)
+
+// newTestServer returns a server with the router "default", backed by a
+// fake router with an enabled adsl route and a disabled 4g route.
+func newTestServer(t *testing.T) (*Server, *routerostest.Server) {
+	t.Helper()
+
+	srv := routerostest.NewServer(
+		routerostest.Route(map[string]string{"comment": "upstream route to adsl", "gateway": "pppoe-out1"}),
+		routerostest.Route(map[string]string{"comment": "backup route via 4g", "gateway": "lte1", "disabled": "true", "active": "false"}),
+	)
+	t.Cleanup(srv.Close)
+
+	c := microtik.Config{
+		Address:  srv.Host(),
+		Port:     srv.Port(),
+		Username: "admin",
+	}
+
+	m := microtik.New(c,
+		microtik.Timeout(time.Second),
+		microtik.RouteID("adsl", "upstream route to adsl"),
+		microtik.RouteID("4g", "backup route via 4g"),
+	)
+	t.Cleanup(m.Close)
+
+	s := New(AddRouter("default", Router{
+		Microtik: m,
+		Routes:   []string{"adsl", "4g"},
+	}))
+	s.fileServer = http.NotFoundHandler()
+	s.routes()
+
+	return s, srv
+}
+
+func serve(s *Server, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestHandleRoutes(t *testing.T) {
+
+	s, _ := newTestServer(t)
+
+	for _, path := range []string{"/api/v1.0/routes", "/api/v1.0/routers/default/routes"} {
+		rec := serve(s, "GET", path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d: %s", path, rec.Code, rec.Body)
+		}
+
+		res := map[string]microtik.RouteResult{}
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if !res["adsl"]["active"] || res["adsl"]["disabled"] || res["4g"]["active"] || !res["4g"]["disabled"] {
+			t.Errorf("%s: got %v", path, res)
+		}
+	}
+}
+
+func TestHandleRoute(t *testing.T) {
+
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "GET", "/api/v1.0/route/4G")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+
+	res := microtik.RouteResult{}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res["active"] || !res["disabled"] {
+		t.Errorf("got %v", res)
+	}
+
+	if rec := serve(s, "GET", "/api/v1.0/route/vdsl"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown route: got status %d", rec.Code)
+	}
+
+	if rec := serve(s, "GET", "/api/v1.0/routers/tower/route/adsl"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown router: got status %d", rec.Code)
+	}
+}
+
+func TestHandleRouteEnableDisable(t *testing.T) {
+
+	s, srv := newTestServer(t)
+
+	if rec := serve(s, "POST", "/api/v1.0/route/adsl/disable"); rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, "POST", "/api/v1.0/routers/default/route/4g/enable"); rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+
+	if r := srv.RouteAttributes("*1"); r["disabled"] != "true" {
+		t.Errorf("adsl not disabled: %v", r)
+	}
+
+	// state changing requests must not be accepted through GET
+	if rec := serve(s, "GET", "/api/v1.0/route/adsl/enable"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: got status %d", rec.Code)
+	}
+	if r := srv.RouteAttributes("*1"); r["disabled"] != "true" {
+		t.Errorf("adsl enabled through GET: %v", r)
+	}
+	if r := srv.RouteAttributes("*2"); r["disabled"] != "false" {
+		t.Errorf("4g not enabled: %v", r)
+	}
+
+	srv.Fail("/ip/route/set", "failure: route is read-only")
+	rec := serve(s, "POST", "/api/v1.0/route/adsl/enable")
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("router error: got status %d", rec.Code)
+	}
+}
+
+func TestHandleReset4G(t *testing.T) {
+
+	s, srv := newTestServer(t)
+
+	if rec := serve(s, "POST", "/api/v1.0/reset4g"); rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+	if resets := srv.PowerResets(); len(resets) != 1 || resets[0] != "5s" {
+		t.Errorf("got power resets %v", resets)
+	}
+
+	srv.Fail("/system/routerboard/usb/power-reset", "no such command prefix")
+	if rec := serve(s, "POST", "/api/v1.0/reset4g"); rec.Code != http.StatusBadGateway {
+		t.Errorf("router error: got status %d", rec.Code)
+	}
+}
+
+func TestHandleRoutesErrors(t *testing.T) {
+
+	s, srv := newTestServer(t)
+
+	// the route of the 4g uplink has been deleted
+	srv.Handle("/ip/route/print", func(routerostest.Command) ([]map[string]string, error) {
+		return []map[string]string{{".id": "*1", "comment": "upstream route to adsl", "disabled": "false", "active": "true"}}, nil
+	})
+	if rec := serve(s, "GET", "/api/v1.0/routes"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing route: got status %d", rec.Code)
+	}
+
+	srv.Fail("/ip/route/print", "not enough permissions (9)")
+	if rec := serve(s, "GET", "/api/v1.0/routes"); rec.Code != http.StatusBadGateway {
+		t.Errorf("router error: got status %d", rec.Code)
+	}
+
+	srv.Close()
+	if rec := serve(s, "GET", "/api/v1.0/routes"); rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("unreachable router: got status %d", rec.Code)
+	}
+}
+
+func TestHandleRoutesAuth(t *testing.T) {
+
+	srv := routerostest.NewServer(routerostest.Credentials("admin", "secret"))
+	t.Cleanup(srv.Close)
+
+	m := microtik.New(microtik.Config{Address: srv.Host(), Port: srv.Port(), Username: "admin", Password: "wrong"},
+		microtik.RouteID("adsl", "upstream route to adsl"))
+	t.Cleanup(m.Close)
+
+	s := New(AddRouter("default", Router{Microtik: m, Routes: []string{"adsl"}}))
+	s.fileServer = http.NotFoundHandler()
+	s.routes()
+
+	if rec := serve(s, "GET", "/api/v1.0/routes"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d", rec.Code)
+	}
+}
+
+func TestHandleRouteTransaction(t *testing.T) {
+
+	s, srv := newTestServer(t)
+
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	s.routers["default"].Probe = func() error {
+		close(probing)
+		<-release
+		return nil
+	}
+
+	postBody := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1.0/routes/transaction", strings.NewReader(body)))
+		return rec
+	}
+	post := func() *httptest.ResponseRecorder {
+		return postBody(`{"changes": [{"route": "4g", "disabled": false}]}`)
+	}
+
+	// invalid transactions are rejected before they are started
+	invalid := []struct {
+		body   string
+		status int
+	}{
+		{`{"changes": []}`, http.StatusBadRequest},
+		{`{"changes": [{"route": "4g"}]}`, http.StatusBadRequest},
+		{`{"changes": [{"route": "4g", "distance": 1}]}`, http.StatusBadRequest},
+		{`{"changes": [{"route": "4g", "disabled": false}, {"route": "4g", "disabled": true}]}`, http.StatusBadRequest},
+		{`{"changes": [{"route": "vdsl", "disabled": false}]}`, http.StatusNotFound},
+	}
+	for _, tc := range invalid {
+		if rec := postBody(tc.body); rec.Code != tc.status {
+			t.Errorf("%s: got status %d, expected %d", tc.body, rec.Code, tc.status)
+		}
+	}
+
+	if rec := serve(s, "GET", "/api/v1.0/routes/transaction/status"); rec.Code != http.StatusNotFound {
+		t.Errorf("status before the first transaction: got status %d", rec.Code)
+	}
+
+	if rec := post(); rec.Code != http.StatusAccepted {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+
+	<-probing
+
+	// the routes are locked until the transaction has finished
+	if rec := post(); rec.Code != http.StatusConflict {
+		t.Errorf("second transaction: got status %d", rec.Code)
+	}
+	if rec := serve(s, "POST", "/api/v1.0/route/adsl/disable"); rec.Code != http.StatusConflict {
+		t.Errorf("route modified during the transaction: got status %d", rec.Code)
+	}
+
+	close(release)
+
+	status := TransactionStatus{Running: true}
+	for i := 0; status.Running && i < 100; i++ {
+		time.Sleep(time.Millisecond * 10)
+		rec := serve(s, "GET", "/api/v1.0/routes/transaction/status")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if status.Running || !status.Applied || !status.Verified || status.Duration <= 0 {
+		t.Errorf("unexpected transaction status %+v", status)
+	}
+	if r := srv.RouteAttributes("*2"); r["disabled"] != "false" {
+		t.Errorf("4g not enabled: %v", r)
+	}
+}
+
+func TestHandleStatus4GUnknownRouter(t *testing.T) {
+
+	s, _ := newTestServer(t)
+
+	for _, source := range []string{"mf823", "router"} {
+		rec := serve(s, "GET", "/api/v1.0/routers/tower/status4g?source="+source)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: got status %d", source, rec.Code)
+		}
+	}
+}
+
+func TestHandleRouterTraceroute(t *testing.T) {
+
+	s, srv := newTestServer(t)
+
+	var args map[string]string
+	srv.Handle("/tool/traceroute", func(cmd routerostest.Command) ([]map[string]string, error) {
+		args = cmd.Args
+		return []map[string]string{{"address": "10.0.0.1", "sent": "1", "loss": "0%"}}, nil
+	})
+
+	// the amount of probes is limited, so that the reply is sent before
+	// the WriteTimeout of the webserver
+	for _, query := range []string{"count=4", "max_hops=0", "max_hops=16", "count=3&max_hops=31"} {
+		rec := serve(s, "GET", "/api/v1.0/router/traceroute/1.1.1.1?"+query)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d", query, rec.Code)
+		}
+	}
+	if args != nil {
+		t.Errorf("traceroute started with invalid options %v", args)
+	}
+
+	rec := serve(s, "GET", "/api/v1.0/router/traceroute/1.1.1.1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+	if args["count"] != "1" || args["max-hops"] != "15" {
+		t.Errorf("unexpected arguments %v", args)
+	}
+}
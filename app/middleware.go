@@ -22,3 +22,28 @@ func (s *Server) apiRedirectRouter(next http.Handler) http.Handler {
 		next.ServeHTTP(w, req)
 	})
 }
+
+// cors is an http middleware which sets the CORS headers for requests
+// from the allowed origins. Preflight requests are answered directly.
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+		origin := req.Header.Get("Origin")
+
+		for _, o := range s.corsOrigins {
+			if o == "*" || o == origin {
+				w.Header().Set("Access-Control-Allow-Origin", o)
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Add("Vary", "Origin")
+				break
+			}
+		}
+
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
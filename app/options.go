@@ -4,7 +4,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/dh1tw/infractl/microtik"
+	"github.com/dh1tw/infractl/failover"
 )
 
 // Address is a functional option to set the address of the webserver
@@ -30,11 +30,11 @@ func ErrorCh(ch chan struct{}) func(*Server) {
 	}
 }
 
-// Microtik is a functional option which sets an instance of a Microtik
-// router
-func Microtik(m *microtik.Microtik) func(*Server) {
+// Failover is a functional option which sets the failover controller
+// whose status will be exposed through the API
+func Failover(c *failover.Controller) func(*Server) {
 	return func(s *Server) {
-		s.microtik = m
+		s.failover = c
 	}
 }
 
@@ -54,6 +54,15 @@ func Mf823Parameters(p []string) func(*Server) {
 	}
 }
 
+// LTESource is a functional option which sets the source of the 4G status:
+// "mf823" (ZTE MF823 web interface, default) or "router" (LTE interface of
+// the microtik router)
+func LTESource(source string) func(*Server) {
+	return func(s *Server) {
+		s.lteSource = source
+	}
+}
+
 // PingAddress sets the hosts to be pinged
 func PingAddress(addresses []string) func(*Server) {
 	return func(s *Server) {
@@ -91,6 +100,31 @@ func PingSamples(samples int) func(*Server) {
 	}
 }
 
+// PingHistory sets the amount of results per host which are kept in the
+// ping history
+func PingHistory(size int) func(*Server) {
+	return func(s *Server) {
+		s.pingHistorySize = size
+	}
+}
+
+// PingUplink assigns a pinged host to an uplink (e.g. a route name). This
+// allows the web interface to display the connectivity of each uplink.
+func PingUplink(uplink, host string) func(*Server) {
+	return func(s *Server) {
+		s.pingUplinks[strings.ToLower(uplink)] = host
+	}
+}
+
+// Events enables listening for the changes of the routes and interfaces
+// on the routers. The events are published through the API and the latest
+// events (size) are kept in a history.
+func Events(size int) func(*Server) {
+	return func(s *Server) {
+		s.events = newEventBus(size)
+	}
+}
+
 // Service authorizes the webserver to control a systemd service. Services can
 // either be specified with or without the extension ".service"
 func Service(serviceName string) func(*Server) {
@@ -101,10 +135,53 @@ func Service(serviceName string) func(*Server) {
 	}
 }
 
-// Route authorizes the webserver to access a microtik route
-func Route(routeName string) func(*Server) {
+// Authentication adds an authenticator to the webserver. If no
+// authenticator is set, all API calls are accessible without credentials.
+func Authentication(a Authenticator) func(*Server) {
+	return func(s *Server) {
+		s.authenticators = append(s.authenticators, a)
+	}
+}
+
+// CORSOrigins sets the origins which are allowed to access the API from
+// within a browser ("*" allows all origins). By default only the web
+// interface served by the server itself (same origin) has access.
+func CORSOrigins(origins []string) func(*Server) {
+	return func(s *Server) {
+		s.corsOrigins = origins
+	}
+}
+
+// TLS enables HTTPS with the provided certificate and key files. If
+// neither of the files exists, a self-signed certificate will be generated.
+// Changes of the files are picked up without restarting the webserver.
+func TLS(certFile, keyFile string) func(*Server) {
+	return func(s *Server) {
+		s.tlsCert = certFile
+		s.tlsKey = keyFile
+	}
+}
+
+// ClientCA sets a file containing the CA certificate(s) against which
+// client certificates will be verified (mTLS). Requires TLS.
+func ClientCA(caFile string) func(*Server) {
+	return func(s *Server) {
+		s.clientCA = caFile
+	}
+}
+
+// TLSHosts sets additional host names and ip addresses for which a
+// generated self-signed certificate will be valid
+func TLSHosts(hosts []string) func(*Server) {
+	return func(s *Server) {
+		s.tlsHosts = hosts
+	}
+}
+
+// HTTPRedirectPort sets the port on which plain HTTP requests will be
+// redirected to HTTPS. Requires TLS.
+func HTTPRedirectPort(port int) func(*Server) {
 	return func(s *Server) {
-		rName := strings.ToLower(routeName)
-		s.mtRoutes = append(s.mtRoutes, rName)
+		s.redirectPort = port
 	}
 }
@@ -0,0 +1,103 @@
+package webserver
+
+import (
+	"time"
+
+	"github.com/dh1tw/infractl/connectivity"
+)
+
+// PingSample is a single entry in the ping history of a host.
+type PingSample struct {
+	Timestamp time.Time     `json:"timestamp"`
+	RTT       time.Duration `json:"rtt"`
+	Loss      float64       `json:"loss"`
+	Failed    bool          `json:"failed"`
+}
+
+// PingHostStatus contains the latest ping result and the history of
+// a host which is pinged by the background job.
+type PingHostStatus struct {
+	connectivity.PingResult
+	Timestamp time.Time    `json:"timestamp"`
+	History   []PingSample `json:"history"`
+}
+
+// PingStatus is the result of the background ping job, as served by the
+// ping API endpoint. The results are considered stale if they haven't
+// been updated within two ping intervals.
+type PingStatus struct {
+	Enabled  bool                      `json:"enabled"`
+	Interval time.Duration             `json:"interval"`
+	Updated  time.Time                 `json:"updated"`
+	Stale    bool                      `json:"stale"`
+	Hosts    map[string]PingHostStatus `json:"hosts"`
+	Uplinks  map[string]string         `json:"uplinks"`
+}
+
+func (s *Server) startPing(interval time.Duration) {
+
+	ticker := time.NewTicker(interval).C
+
+	for {
+		s.Lock()
+		hosts := s.pingHosts
+		timeout := s.pingTimeout
+		samples := s.pingSamples
+		s.Unlock()
+
+		res := connectivity.PingHosts(hosts, timeout, samples)
+		now := time.Now()
+
+		s.Lock()
+		s.pingResults = res
+		s.pingUpdated = now
+		for host, r := range res {
+			sample := PingSample{
+				Timestamp: now,
+				RTT:       r.RTT,
+				Loss:      r.Loss,
+				Failed:    r.Failed,
+			}
+			h := append(s.pingHistory[host], sample)
+			if len(h) > s.pingHistorySize {
+				h = h[len(h)-s.pingHistorySize:]
+			}
+			s.pingHistory[host] = h
+		}
+		s.Unlock()
+		// make sure the hosts are pinged immediately after startup
+		<-ticker
+	}
+}
+
+// pingStatus returns a copy of the cached results of the background
+// ping job.
+func (s *Server) pingStatus() PingStatus {
+	s.Lock()
+	defer s.Unlock()
+
+	ps := PingStatus{
+		Enabled:  s.pingEnabled,
+		Interval: s.pingInterval,
+		Updated:  s.pingUpdated,
+		Stale:    time.Since(s.pingUpdated) > 2*s.pingInterval,
+		Hosts:    make(map[string]PingHostStatus),
+		Uplinks:  make(map[string]string),
+	}
+
+	for host, r := range s.pingResults {
+		history := make([]PingSample, len(s.pingHistory[host]))
+		copy(history, s.pingHistory[host])
+		ps.Hosts[host] = PingHostStatus{
+			PingResult: r,
+			Timestamp:  s.pingUpdated,
+			History:    history,
+		}
+	}
+
+	for uplink, host := range s.pingUplinks {
+		ps.Uplinks[uplink] = host
+	}
+
+	return ps
+}
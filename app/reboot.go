@@ -0,0 +1,162 @@
+package webserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+)
+
+// confirmTimeout is the time within which a reboot has to be confirmed
+const confirmTimeout = time.Minute
+
+// RebootStatus is the status of the current (or last) reboot of a router.
+type RebootStatus struct {
+	Running bool `json:"running"`
+	microtik.RebootResult
+	Error string `json:"error,omitempty"`
+}
+
+// rebootToken has to be provided in order to confirm a reboot.
+type rebootToken struct {
+	token   string
+	expires time.Time
+}
+
+func newRebootToken() (rebootToken, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return rebootToken{}, err
+	}
+	return rebootToken{
+		token:   hex.EncodeToString(b),
+		expires: time.Now().Add(confirmTimeout),
+	}, nil
+}
+
+// handleRouterReboot reboots the microtik router. The reboot has to be
+// confirmed: a request without a valid token (body: {"confirm": "<token>"})
+// is answered with 428 and a new token, which is valid for one minute. The
+// confirmed reboot is executed in the background (202); its progress is
+// reported through the reboot status.
+func (s *Server) handleRouterReboot(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	body := struct {
+		Confirm string `json:"confirm"`
+	}{}
+
+	// an empty body requests a confirmation token
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("unable to decode reboot request: %v", err)))
+			return
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if status, ok := s.reboots[rt]; ok && status.Running {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("reboot already in progress"))
+		return
+	}
+
+	t, ok := s.rebootTokens[rt]
+	if !ok || len(body.Confirm) == 0 || body.Confirm != t.token || time.Now().After(t.expires) {
+		t, err := newRebootToken()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		s.rebootTokens[rt] = t
+
+		res := struct {
+			ConfirmToken string    `json:"confirm_token"`
+			Expires      time.Time `json:"expires"`
+		}{t.token, t.expires}
+
+		w.WriteHeader(http.StatusPreconditionRequired)
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			log.Println("unable to encode reboot token to json:", err)
+		}
+		return
+	}
+
+	delete(s.rebootTokens, rt)
+
+	status := &RebootStatus{
+		Running:      true,
+		RebootResult: microtik.RebootResult{Started: time.Now()},
+	}
+	s.reboots[rt] = status
+
+	timeout := rt.RebootTimeout
+	if timeout == 0 {
+		timeout = time.Minute * 5
+	}
+
+	go func() {
+		res, err := rt.Microtik.Reboot(timeout)
+		s.Lock()
+		defer s.Unlock()
+		status.Running = false
+		status.RebootResult = res
+		if err != nil {
+			status.Error = err.Error()
+			log.Println("reboot failed:", err)
+			return
+		}
+		log.Printf("router rebooted, down for %v\n", res.Downtime.Round(time.Second))
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Println("unable to encode reboot status to json:", err)
+	}
+}
+
+// handleRouterRebootStatus returns the status of the current (or last)
+// reboot of the microtik router.
+func (s *Server) handleRouterRebootStatus(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	status, ok := s.reboots[rt]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("router has not been rebooted"))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode reboot status to json"))
+	}
+}
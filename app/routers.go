@@ -0,0 +1,103 @@
+package webserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dh1tw/infractl/backup"
+	"github.com/dh1tw/infractl/devices"
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/dh1tw/infractl/reset"
+	"github.com/gorilla/mux"
+)
+
+// Router is a microtik router which can be accessed through the API. The
+// routes, the 4G reset and the route transactions are scoped per router.
+type Router struct {
+	Microtik *microtik.Microtik
+	// Routes contains the names of the registered routes whose status
+	// is reported
+	Routes []string
+	// Reset4G is the policy for resetting the 4G modem. If nil, the modem
+	// is reset without touching any routes.
+	Reset4G *reset.Policy
+	// Probe enables route transactions (safe mode). Without a probe,
+	// transactions are rejected.
+	Probe     microtik.Probe
+	TxOptions []microtik.TransactionOption
+	// Uplinks maps the names of the uplinks (e.g. adsl) to the
+	// interfaces of the router (e.g. pppoe-out1)
+	Uplinks map[string]string
+	// LTEInterface is the LTE interface (e.g. lte1) whose status is
+	// reported if the LTE source is "router"
+	LTEInterface string
+	// Backups contains the configuration backups of the router (see
+	// 'infractl router-backup'). If nil, no backups are available.
+	Backups *backup.Store
+	// Devices contains the friendly names of the devices in the network
+	// of the router and whether they are required
+	Devices []devices.Known
+	// RebootTimeout is the maximum time to wait for the router after a
+	// reboot (default: 5 minutes)
+	RebootTimeout time.Duration
+}
+
+// AddRouter is a functional option which makes a microtik router
+// accessible under /api/v1.0/routers/{name}/. The first router added is
+// the default router, unless set otherwise with DefaultRouter.
+func AddRouter(name string, r Router) Option {
+	return func(s *Server) {
+		name = strings.ToLower(name)
+		routes := make([]string, 0, len(r.Routes))
+		for _, route := range r.Routes {
+			routes = append(routes, strings.ToLower(route))
+		}
+		r.Routes = routes
+		s.routers[name] = &r
+		if len(s.defaultRouter) == 0 {
+			s.defaultRouter = name
+		}
+	}
+}
+
+// DefaultRouter is a functional option which sets the router which is
+// accessed through the API paths without a router name (e.g.
+// /api/v1.0/routes).
+func DefaultRouter(name string) Option {
+	return func(s *Server) {
+		s.defaultRouter = strings.ToLower(name)
+	}
+}
+
+// lookupRouter returns the router addressed by the request. Requests
+// without a router name address the default router.
+func (s *Server) lookupRouter(req *http.Request) (*Router, error) {
+
+	name := strings.ToLower(mux.Vars(req)["router"])
+	if len(name) == 0 {
+		name = s.defaultRouter
+	}
+
+	r, ok := s.routers[name]
+	if !ok {
+		if len(name) == 0 {
+			return nil, fmt.Errorf("no microtik instance configured")
+		}
+		return nil, fmt.Errorf("unknown router %s", name)
+	}
+
+	return r, nil
+}
+
+// routerNames returns the sorted names of all routers.
+func (s *Server) routerNames() []string {
+	names := make([]string, 0, len(s.routers))
+	for name := range s.routers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
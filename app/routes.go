@@ -1,16 +1,69 @@
 package webserver
 
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// routes registers the handlers of the API. Endpoints which change the
+// state of a router or a service only accept POST requests.
 func (s *Server) routes() {
-	s.router.HandleFunc("/api/v1.0/reset4g", s.handleReset4G)
-	s.router.HandleFunc("/api/v1.0/status4g", s.handleStatus4G)
-	s.router.HandleFunc("/api/v1.0/ping/{host}", s.handlePing)
-	s.router.HandleFunc("/api/v1.0/services", s.handleServicesList)
-	s.router.HandleFunc("/api/v1.0/service/{service}/start", s.handleServiceStart)
-	s.router.HandleFunc("/api/v1.0/service/{service}/stop", s.handleServiceStop)
-	s.router.HandleFunc("/api/v1.0/service/{service}/restart", s.handleServiceRestart)
-	s.router.HandleFunc("/api/v1.0/routes", s.handleRoutes)
-	s.router.HandleFunc("/api/v1.0/route/{route}", s.handleRoute)
-	s.router.HandleFunc("/api/v1.0/route/{route}/enable", s.handleRouteEnable)
-	s.router.HandleFunc("/api/v1.0/route/{route}/disable", s.handleRouteDisable)
-	s.router.PathPrefix("/").Handler(s.fileServer)
+	s.router.HandleFunc("/api/v1.0/ping", s.authorize(RoleViewer, s.handlePingResults))
+	s.router.HandleFunc("/api/v1.0/ping/{host}", s.authorize(RoleViewer, s.handlePing))
+	s.router.HandleFunc("/api/v1.0/services", s.authorize(RoleViewer, s.handleServicesList))
+	s.router.HandleFunc("/api/v1.0/service/{service}/start", s.authorize(RoleOperator, s.handleServiceStart)).Methods(http.MethodPost)
+	s.router.HandleFunc("/api/v1.0/service/{service}/stop", s.authorize(RoleAdmin, s.handleServiceStop)).Methods(http.MethodPost)
+	s.router.HandleFunc("/api/v1.0/service/{service}/restart", s.authorize(RoleOperator, s.handleServiceRestart)).Methods(http.MethodPost)
+	s.router.HandleFunc("/api/v1.0/routers", s.authorize(RoleViewer, s.handleRouters))
+	s.router.HandleFunc("/api/v1.0/failover", s.authorize(RoleViewer, s.handleFailover))
+	s.router.HandleFunc("/api/v1.0/events", s.authorize(RoleViewer, s.handleEvents))
+	s.router.HandleFunc("/api/v1.0/router/health", s.authorize(RoleViewer, s.handleRouterHealth))
+	s.router.HandleFunc("/api/v1.0/routers/{router}/health", s.authorize(RoleViewer, s.handleRouterHealth))
+	s.router.HandleFunc("/api/v1.0/router/reboot", s.authorize(RoleAdmin, s.handleRouterReboot)).Methods(http.MethodPost)
+	s.router.HandleFunc("/api/v1.0/routers/{router}/reboot", s.authorize(RoleAdmin, s.handleRouterReboot)).Methods(http.MethodPost)
+	s.router.HandleFunc("/api/v1.0/router/reboot/status", s.authorize(RoleViewer, s.handleRouterRebootStatus))
+	s.router.HandleFunc("/api/v1.0/routers/{router}/reboot/status", s.authorize(RoleViewer, s.handleRouterRebootStatus))
+	s.router.HandleFunc("/api/v1.0/router/ping/{host}", s.authorize(RoleViewer, s.handleRouterPing))
+	s.router.HandleFunc("/api/v1.0/routers/{router}/ping/{host}", s.authorize(RoleViewer, s.handleRouterPing))
+	s.router.HandleFunc("/api/v1.0/router/traceroute/{host}", s.authorize(RoleViewer, s.handleRouterTraceroute))
+	s.router.HandleFunc("/api/v1.0/routers/{router}/traceroute/{host}", s.authorize(RoleViewer, s.handleRouterTraceroute))
+
+	// the microtik routers are accessible by their name. The paths without
+	// a router name address the default router.
+	for _, prefix := range []string{"/api/v1.0", "/api/v1.0/routers/{router}"} {
+		s.router.HandleFunc(prefix+"/reset4g", s.authorize(RoleOperator, s.handleReset4G)).Methods(http.MethodPost)
+		s.router.HandleFunc(prefix+"/reset4g/status", s.authorize(RoleViewer, s.handleReset4GStatus))
+		s.router.HandleFunc(prefix+"/status4g", s.authorize(RoleViewer, s.handleStatus4G))
+		s.router.HandleFunc(prefix+"/interfaces", s.authorize(RoleViewer, s.handleInterfaces))
+		s.router.HandleFunc(prefix+"/traffic", s.authorize(RoleViewer, s.handleTraffic))
+		s.router.HandleFunc(prefix+"/address-list/{list}", s.authorize(RoleViewer, s.handleAddressList))
+		s.router.HandleFunc(prefix+"/address-list/{list}/add", s.authorize(RoleOperator, s.handleAddressListAdd)).Methods(http.MethodPost)
+		s.router.HandleFunc(prefix+"/address-list/{list}/remove", s.authorize(RoleOperator, s.handleAddressListRemove)).Methods(http.MethodPost)
+		s.router.HandleFunc(prefix+"/nat", s.authorize(RoleViewer, s.handleNatRules))
+		s.router.HandleFunc(prefix+"/nat/{rule}/enable", s.authorize(RoleOperator, s.handleNatRuleEnable)).Methods(http.MethodPost)
+		s.router.HandleFunc(prefix+"/nat/{rule}/disable", s.authorize(RoleOperator, s.handleNatRuleDisable)).Methods(http.MethodPost)
+		s.router.HandleFunc(prefix+"/devices", s.authorize(RoleViewer, s.handleDevices))
+		s.router.HandleFunc(prefix+"/backups", s.authorize(RoleViewer, s.handleBackups))
+		s.router.HandleFunc(prefix+"/backup/diff", s.authorize(RoleAdmin, s.handleBackupDiff)).Methods(http.MethodGet)
+		s.router.HandleFunc(prefix+"/backup/diff", s.authorize(RoleAdmin, s.handleBackupDiffLive)).Methods(http.MethodPost)
+		s.router.HandleFunc(prefix+"/routes", s.authorize(RoleViewer, s.handleRoutes))
+		s.router.HandleFunc(prefix+"/routes/all", s.authorize(RoleViewer, s.handleRoutesAll))
+		s.router.HandleFunc(prefix+"/routes/transaction", s.authorize(RoleOperator, s.handleRouteTransaction)).Methods(http.MethodPost)
+		s.router.HandleFunc(prefix+"/routes/transaction/status", s.authorize(RoleViewer, s.handleRouteTransactionStatus))
+		s.router.HandleFunc(prefix+"/route/{route}", s.authorize(RoleViewer, s.handleRoute))
+		s.router.HandleFunc(prefix+"/route/{route}/enable", s.authorize(RoleOperator, s.handleRouteEnable)).Methods(http.MethodPost)
+		s.router.HandleFunc(prefix+"/route/{route}/disable", s.authorize(RoleOperator, s.handleRouteDisable)).Methods(http.MethodPost)
+	}
+
+	// the web interface is served on all paths outside of the API, so
+	// that a request with the wrong method on an API path is answered
+	// with 405 instead of being passed on to the file server
+	s.router.PathPrefix("/").MatcherFunc(notAPI).Handler(s.fileServer)
+}
+
+// notAPI matches all requests outside of the API.
+func notAPI(req *http.Request, m *mux.RouteMatch) bool {
+	return !strings.HasPrefix(req.URL.Path, "/api/")
 }
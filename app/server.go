@@ -1,15 +1,20 @@
 package webserver
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"regexp"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/dh1tw/infractl/connectivity"
-	"github.com/dh1tw/infractl/microtik"
+	"github.com/dh1tw/infractl/failover"
 	"github.com/markbates/pkger"
 
 	"github.com/gorilla/mux"
@@ -25,17 +30,34 @@ type Server struct {
 	apiMatch        *regexp.Regexp
 	errorCh         chan struct{}
 	closeOnce       sync.Once
-	microtik        *microtik.Microtik
+	routers         map[string]*Router
+	defaultRouter   string
+	failover        *failover.Controller
 	mf823Address    string
 	mf823Parameters []string
+	lteSource       string
 	pingEnabled     bool
 	pingInterval    time.Duration
 	pingHosts       []string
 	pingSamples     int
 	pingTimeout     time.Duration
 	pingResults     connectivity.PingResults
+	pingUpdated     time.Time
+	pingHistory     map[string][]PingSample
+	pingHistorySize int
+	pingUplinks     map[string]string
+	events          *eventBus
+	reboots         map[*Router]*RebootStatus
+	transactions    map[*Router]*TransactionStatus
+	rebootTokens    map[*Router]rebootToken
 	services        map[string]struct{}
-	mtRoutes        []string
+	authenticators  []Authenticator
+	corsOrigins     []string
+	tlsCert         string
+	tlsKey          string
+	clientCA        string
+	tlsHosts        []string
+	redirectPort    int
 }
 
 // Option is the type used for functional options
@@ -56,10 +78,18 @@ func New(opts ...Option) *Server {
 		pingTimeout:     time.Second * 9,
 		pingSamples:     1,
 		pingResults:     make(connectivity.PingResults),
+		pingHistory:     make(map[string][]PingSample),
+		pingHistorySize: 60,
+		pingUplinks:     make(map[string]string),
 		mf823Parameters: []string{},
+		lteSource:       "mf823",
 		errorCh:         make(chan struct{}),
 		services:        make(map[string]struct{}),
-		mtRoutes:        []string{},
+		routers:         make(map[string]*Router),
+		reboots:         make(map[*Router]*RebootStatus),
+		transactions:    make(map[*Router]*TransactionStatus),
+		rebootTokens:    make(map[*Router]rebootToken),
+		authenticators:  []Authenticator{},
 	}
 
 	for _, opt := range opts {
@@ -89,43 +119,111 @@ func (s *Server) Serve() {
 		go s.startPing(s.pingInterval)
 	}
 
+	if s.events != nil {
+		s.startEvents()
+	}
+
 	url := fmt.Sprintf("%s:%d", s.address, s.port)
 
 	s.fileServer = http.FileServer(pkger.Dir("/web/dist"))
 
 	s.routes()
 
-	// Listen for incoming connections.
-	log.Printf("listening on %s for HTTP connections\n", url)
+	if len(s.authenticators) == 0 {
+		log.Println("WARNING: authentication disabled, the API is accessible for everybody")
+	}
 
+	// traceroutes from the router may take up to 30s
 	srv := &http.Server{
 		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		WriteTimeout: 60 * time.Second,
 		Addr:         url,
-		Handler:      s.apiRedirectRouter((s.router)),
+		Handler:      s.cors(s.apiRedirectRouter(s.router)),
+	}
+
+	if len(s.tlsCert) == 0 {
+		// Listen for incoming connections.
+		log.Printf("listening on %s for HTTP connections\n", url)
+		if err := srv.ListenAndServe(); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	// generate a self-signed certificate on the first start
+	if !fileExists(s.tlsCert) && !fileExists(s.tlsKey) {
+		log.Printf("generating self-signed certificate %s\n", s.tlsCert)
+		hosts := append([]string{s.address}, s.tlsHosts...)
+		if err := generateSelfSignedCert(s.tlsCert, s.tlsKey, hosts); err != nil {
+			log.Println("unable to generate self-signed certificate:", err)
+			return
+		}
+	}
+
+	certs, err := newCertReloader(s.tlsCert, s.tlsKey)
+	if err != nil {
+		log.Println(err)
+		return
 	}
+	go certs.watch()
 
-	err := srv.ListenAndServe()
+	tlsConfig, err := s.tlsConfig()
 	if err != nil {
 		log.Println(err)
 		return
 	}
+	tlsConfig.GetCertificate = certs.GetCertificate
+	srv.TLSConfig = tlsConfig
+
+	if s.redirectPort > 0 {
+		redirectURL := net.JoinHostPort(s.address, strconv.Itoa(s.redirectPort))
+		log.Printf("redirecting HTTP connections on %s to HTTPS\n", redirectURL)
+		go func() {
+			redirectSrv := &http.Server{
+				ReadTimeout:  5 * time.Second,
+				WriteTimeout: 10 * time.Second,
+				Addr:         redirectURL,
+				Handler:      s.redirectHTTPS(),
+			}
+			if err := redirectSrv.ListenAndServe(); err != nil {
+				log.Println(err)
+			}
+		}()
+	}
+
+	// Listen for incoming connections.
+	log.Printf("listening on %s for HTTPS connections\n", url)
+	if err := srv.ListenAndServeTLS("", ""); err != nil {
+		log.Println(err)
+	}
 }
 
-func (s *Server) startPing(interval time.Duration) {
+// tlsConfig returns the TLS configuration of the server. If a client CA
+// has been set, client certificates will be verified against it (mTLS).
+// Client certificates are optional, so that clients can still
+// authenticate with tokens or passwords.
+func (s *Server) tlsConfig() (*tls.Config, error) {
 
-	ticker := time.NewTicker(interval).C
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
 
-	for {
-		s.Lock()
-		hosts := s.pingHosts
-		s.Unlock()
+	if len(s.clientCA) == 0 {
+		return tlsConfig, nil
+	}
+
+	pem, err := ioutil.ReadFile(s.clientCA)
+	if err != nil {
+		return nil, err
+	}
 
-		res := connectivity.PingHosts(hosts, time.Second*2, 1)
-		s.Lock()
-		s.pingResults = res
-		s.Unlock()
-		// make sure the hosts are pinged immediately after startup
-		<-ticker
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid certificates found in %s", s.clientCA)
 	}
+
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+	return tlsConfig, nil
 }
@@ -0,0 +1,209 @@
+package webserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// certReloader holds the TLS certificate of the server and reloads it
+// whenever the certificate or key file changes on disk. This allows to
+// renew the certificate without restarting the webserver.
+type certReloader struct {
+	sync.Mutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	r.cert = &cert
+	r.Unlock()
+	return nil
+}
+
+// GetCertificate can be used as tls.Config.GetCertificate callback.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.cert, nil
+}
+
+// watch reloads the certificate when the certificate or key file has
+// been modified. Since certificates are often replaced by renaming a new
+// file, the directories containing the files are watched instead of the
+// files themselves. If the new certificate is invalid, the old one stays
+// in use. This function blocks and should be executed in a go routine.
+func (r *certReloader) watch() {
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Println("unable to watch the TLS certificate:", err)
+		return
+	}
+	defer watcher.Close()
+
+	certFile, _ := filepath.Abs(r.certFile)
+	keyFile, _ := filepath.Abs(r.keyFile)
+
+	for _, dir := range []string{filepath.Dir(certFile), filepath.Dir(keyFile)} {
+		if err := watcher.Add(dir); err != nil {
+			log.Println("unable to watch the TLS certificate:", err)
+			return
+		}
+	}
+
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			name, _ := filepath.Abs(ev.Name)
+			if name != certFile && name != keyFile {
+				continue
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				// the key and certificate might not have been written both yet
+				log.Println("unable to reload the TLS certificate:", err)
+				continue
+			}
+			log.Println("TLS certificate reloaded")
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Println("watching the TLS certificate:", err)
+		}
+	}
+}
+
+// generateSelfSignedCert creates a self-signed certificate and a private key
+// and writes them PEM encoded to the given files. The certificate is valid
+// for the local hostname, localhost and the provided hosts (names or
+// ip addresses).
+func generateSelfSignedCert(certFile, keyFile string, hosts []string) error {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"infractl"}, CommonName: "infractl"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		hosts = append(hosts, hostname)
+	}
+	hosts = append(hosts, "localhost", "127.0.0.1", "::1")
+
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			if !ip.IsUnspecified() {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			}
+			continue
+		}
+		template.DNSNames = append(template.DNSNames, h)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.MkdirAll(filepath.Dir(f), 0700); err != nil {
+			return err
+		}
+	}
+
+	if err := writePem(keyFile, "EC PRIVATE KEY", keyDer, 0600); err != nil {
+		return err
+	}
+
+	return writePem(certFile, "CERTIFICATE", der, 0644)
+}
+
+func writePem(file, blockType string, der []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fileExists returns true if the file exists.
+func fileExists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
+
+// redirectHTTPS returns a handler which redirects all requests to the
+// HTTPS port of the server.
+func (s *Server) redirectHTTPS() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host, _, err := net.SplitHostPort(req.Host)
+		if err != nil {
+			host = req.Host
+		}
+		target := fmt.Sprintf("https://%s%s", net.JoinHostPort(host, strconv.Itoa(s.port)), req.URL.RequestURI())
+		http.Redirect(w, req, target, http.StatusMovedPermanently)
+	})
+}
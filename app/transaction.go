@@ -0,0 +1,162 @@
+package webserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+)
+
+// TransactionStatus is the status of the current (or last) route
+// transaction of a router.
+type TransactionStatus struct {
+	Running bool      `json:"running"`
+	Started time.Time `json:"started"`
+	microtik.TransactionResult
+}
+
+// routeChange is the JSON representation of a microtik.RouteChange. Only
+// the provided attributes are modified.
+type routeChange struct {
+	Route        string  `json:"route"`
+	Disabled     *bool   `json:"disabled"`
+	Distance     *int    `json:"distance"`
+	Gateway      *string `json:"gateway"`
+	CheckGateway *string `json:"check_gateway"`
+	Comment      *string `json:"comment"`
+}
+
+func (c routeChange) ops() []microtik.RouteOp {
+	ops := []microtik.RouteOp{}
+	if c.Disabled != nil {
+		if *c.Disabled {
+			ops = append(ops, microtik.Disable())
+		} else {
+			ops = append(ops, microtik.Enable())
+		}
+	}
+	if c.Distance != nil {
+		ops = append(ops, microtik.SetDistance(*c.Distance))
+	}
+	if c.Gateway != nil {
+		ops = append(ops, microtik.SetGateway(*c.Gateway))
+	}
+	if c.CheckGateway != nil {
+		ops = append(ops, microtik.SetCheckGateway(*c.CheckGateway))
+	}
+	if c.Comment != nil {
+		ops = append(ops, microtik.SetComment(*c.Comment))
+	}
+	return ops
+}
+
+// handleRouteTransaction modifies one or more routes in safe mode. If the
+// connectivity can't be verified afterwards, the changes are rolled back.
+// Since the verification and the rollback can take longer than the write
+// timeout of the server, the transaction is executed in the background
+// (202); its outcome is reported through the transaction status.
+func (s *Server) handleRouteTransaction(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if rt.Probe == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("safe mode not enabled"))
+		return
+	}
+
+	body := struct {
+		Changes []routeChange `json:"changes"`
+	}{}
+
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("unable to decode transaction: %v", err)))
+		return
+	}
+
+	changes := []microtik.RouteChange{}
+	for _, c := range body.Changes {
+		changes = append(changes, microtik.RouteChange{
+			Route: c.Route,
+			Ops:   c.ops(),
+		})
+	}
+
+	// invalid changes are rejected before the transaction is started
+	if err := rt.Microtik.CheckTransaction(changes); err != nil {
+		w.WriteHeader(routerErrorStatus(err))
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if status, ok := s.transactions[rt]; ok && status.Running {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("transaction already in progress"))
+		return
+	}
+
+	status := &TransactionStatus{
+		Running: true,
+		Started: time.Now(),
+	}
+	s.transactions[rt] = status
+
+	go func() {
+		res, err := rt.Microtik.Transaction(rt.Probe, changes, rt.TxOptions...)
+		s.Lock()
+		defer s.Unlock()
+		status.Running = false
+		status.TransactionResult = res
+		if err != nil {
+			log.Println("route transaction failed:", err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Println("unable to encode transaction status to json:", err)
+	}
+}
+
+// handleRouteTransactionStatus returns the status of the current (or last)
+// route transaction of the microtik router.
+func (s *Server) handleRouteTransactionStatus(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rt, err := s.lookupRouter(req)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	status, ok := s.transactions[rt]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("no route transaction executed"))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode transaction status to json"))
+	}
+}
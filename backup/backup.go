@@ -0,0 +1,217 @@
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File names within a snapshot directory
+const (
+	ExportFile = "export.rsc"
+	BackupFile = "system.backup"
+)
+
+// timeFormat is used for the names of the snapshot directories. Snapshots
+// taken within the same second get a counter as suffix (e.g.
+// 20200102-150405-2).
+const timeFormat = "20060102-150405"
+
+// Snapshot is a stored backup of the router configuration.
+type Snapshot struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+	Dir  string    `json:"dir"`
+	// HasBackup is true if the snapshot contains a binary backup
+	HasBackup bool `json:"has_backup"`
+	seq       int
+}
+
+// parseName returns the time and the counter of a snapshot directory name.
+func parseName(name string) (time.Time, int, error) {
+	seq := 1
+	if len(name) > len(timeFormat) {
+		n, err := strconv.Atoi(strings.TrimPrefix(name[len(timeFormat):], "-"))
+		if err != nil || n < 2 || name[len(timeFormat)] != '-' {
+			return time.Time{}, 0, fmt.Errorf("invalid snapshot name %s", name)
+		}
+		seq = n
+		name = name[:len(timeFormat)]
+	}
+	t, err := time.ParseInLocation(timeFormat, name, time.Local)
+	return t, seq, err
+}
+
+// Export returns the configuration script of the snapshot.
+func (s Snapshot) Export() (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.Dir, ExportFile))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Store keeps the snapshots of a router in timestamped directories
+// (e.g. <dir>/20200102-150405). Only the latest snapshots are kept.
+type Store struct {
+	dir  string
+	keep int
+}
+
+// NewStore returns a Store which keeps up to keep snapshots in dir. If
+// keep is 0, all snapshots are kept.
+func NewStore(dir string, keep int) *Store {
+	return &Store{
+		dir:  dir,
+		keep: keep,
+	}
+}
+
+// Save stores the configuration script and the (optional) binary backup
+// in a new snapshot and removes the snapshots exceeding the retention.
+func (s *Store) Save(export string, backup []byte) (Snapshot, error) {
+
+	now := time.Now()
+	snap := Snapshot{
+		Name:      now.Format(timeFormat),
+		Time:      now,
+		HasBackup: backup != nil,
+		seq:       1,
+	}
+
+	// the backups contain credentials
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return snap, err
+	}
+
+	// an existing snapshot of the same second is never overwritten
+	for {
+		if snap.seq > 1 {
+			snap.Name = fmt.Sprintf("%s-%d", now.Format(timeFormat), snap.seq)
+		}
+		snap.Dir = filepath.Join(s.dir, snap.Name)
+		err := os.Mkdir(snap.Dir, 0700)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return snap, err
+		}
+		snap.seq++
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(snap.Dir, ExportFile), []byte(export), 0600); err != nil {
+		return snap, err
+	}
+
+	if backup != nil {
+		if err := ioutil.WriteFile(filepath.Join(snap.Dir, BackupFile), backup, 0600); err != nil {
+			return snap, err
+		}
+	}
+
+	return snap, s.prune()
+}
+
+// List returns the stored snapshots, sorted from the oldest to the latest.
+func (s *Store) List() ([]Snapshot, error) {
+
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Snapshot{}, nil
+		}
+		return nil, err
+	}
+
+	snaps := []Snapshot{}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		t, seq, err := parseName(e.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join(s.dir, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, ExportFile)); err != nil {
+			continue
+		}
+		_, err = os.Stat(filepath.Join(dir, BackupFile))
+		snaps = append(snaps, Snapshot{
+			Name:      e.Name(),
+			Time:      t,
+			Dir:       dir,
+			HasBackup: err == nil,
+			seq:       seq,
+		})
+	}
+
+	sort.Slice(snaps, func(i, j int) bool {
+		if !snaps[i].Time.Equal(snaps[j].Time) {
+			return snaps[i].Time.Before(snaps[j].Time)
+		}
+		return snaps[i].seq < snaps[j].seq
+	})
+
+	return snaps, nil
+}
+
+// Latest returns the most recent snapshot.
+func (s *Store) Latest() (Snapshot, error) {
+
+	snaps, err := s.List()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if len(snaps) == 0 {
+		return Snapshot{}, fmt.Errorf("no backup found in %s", s.dir)
+	}
+
+	return snaps[len(snaps)-1], nil
+}
+
+// Get returns the snapshot with the given name.
+func (s *Store) Get(name string) (Snapshot, error) {
+
+	snaps, err := s.List()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	for _, snap := range snaps {
+		if snap.Name == name {
+			return snap, nil
+		}
+	}
+
+	return Snapshot{}, fmt.Errorf("backup %s not found in %s", name, s.dir)
+}
+
+// prune removes the oldest snapshots exceeding the retention.
+func (s *Store) prune() error {
+
+	if s.keep <= 0 {
+		return nil
+	}
+
+	snaps, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(snaps)-s.keep; i++ {
+		if err := os.RemoveAll(snaps[i].Dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreSave(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "infractl-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewStore(filepath.Join(dir, "router"), 0)
+
+	// snapshots taken within the same second must not overwrite each other
+	exports := []string{"/ip address\nadd address=10.0.0.1/24\n", "/ip address\nadd address=10.0.0.2/24\n", "/ip dns\n"}
+	names := map[string]bool{}
+	for i, export := range exports {
+		var backup []byte
+		if i == 0 {
+			backup = []byte{0x88, 0xac}
+		}
+		snap, err := s.Save(export, backup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names[snap.Name] {
+			t.Fatalf("snapshot %s saved twice", snap.Name)
+		}
+		names[snap.Name] = true
+	}
+
+	snaps, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != len(exports) {
+		t.Fatalf("got %d snapshots, expected %d", len(snaps), len(exports))
+	}
+
+	for i, snap := range snaps {
+		export, err := snap.Export()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if export != exports[i] {
+			t.Errorf("snapshot %d (%s): got export %q, expected %q", i, snap.Name, export, exports[i])
+		}
+		if snap.HasBackup != (i == 0) {
+			t.Errorf("snapshot %d (%s): unexpected HasBackup %v", i, snap.Name, snap.HasBackup)
+		}
+	}
+
+	info, err := os.Stat(snaps[0].Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("got permissions %v, expected 0700", info.Mode().Perm())
+	}
+}
+
+func TestStoreList(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "infractl-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dirs := []string{
+		"20200102-150405-10",
+		"20200102-150405",
+		"20200102-150405-2",
+		"20191231-235959",
+		"20200102-150405-1", // invalid counter
+		"20200102-150405-x", // invalid counter
+		"latest",            // not a snapshot
+		"20200103-120000",   // without an export
+	}
+	for _, d := range dirs {
+		if err := os.Mkdir(filepath.Join(dir, d), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if d == "20200103-120000" {
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, d, ExportFile), []byte(d), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewStore(dir, 0)
+
+	snaps, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"20191231-235959", "20200102-150405", "20200102-150405-2", "20200102-150405-10"}
+	if len(snaps) != len(exp) {
+		t.Fatalf("got %d snapshots %v, expected %v", len(snaps), snaps, exp)
+	}
+	for i, snap := range snaps {
+		if snap.Name != exp[i] {
+			t.Errorf("snapshot %d: got %s, expected %s", i, snap.Name, exp[i])
+		}
+	}
+
+	latest, err := s.Latest()
+	if err != nil || latest.Name != "20200102-150405-10" {
+		t.Errorf("unexpected latest snapshot %v (%v)", latest.Name, err)
+	}
+
+	// the retention removes the oldest snapshots
+	s = NewStore(dir, 2)
+	if err := s.prune(); err != nil {
+		t.Fatal(err)
+	}
+	snaps, err = s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 2 || snaps[0].Name != "20200102-150405-2" {
+		t.Errorf("unexpected snapshots after pruning %v", snaps)
+	}
+}
+
+func TestStoreListMissingDir(t *testing.T) {
+
+	s := NewStore(filepath.Join(os.TempDir(), "infractl-backup-does-not-exist"), 0)
+
+	snaps, err := s.List()
+	if err != nil || len(snaps) != 0 {
+		t.Errorf("got %v (%v), expected no snapshots", snaps, err)
+	}
+}
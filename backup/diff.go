@@ -0,0 +1,179 @@
+package backup
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// contextLines is the amount of unchanged lines shown around a change
+const contextLines = 3
+
+// header matches the first line of an export, which contains the time of
+// the export (e.g. "# jan/02/2020 15:04:05 by RouterOS 6.46.4") and would
+// show up in every diff.
+var header = regexp.MustCompile(`^#.* by RouterOS `)
+
+// Diff returns the unified diff between two configuration scripts. The
+// names are shown in the header of the diff. If the configurations are
+// identical, an empty string is returned.
+func Diff(a, b, nameA, nameB string) string {
+
+	ops := diffLines(exportLines(a), exportLines(b))
+
+	// position of each operation in a and b
+	posA := make([]int, len(ops)+1)
+	posB := make([]int, len(ops)+1)
+	for i, op := range ops {
+		posA[i+1], posB[i+1] = posA[i], posB[i]
+		if op.kind != '+' {
+			posA[i+1]++
+		}
+		if op.kind != '-' {
+			posB[i+1]++
+		}
+	}
+
+	out := strings.Builder{}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// changes which are separated by less than twice the context
+		// are combined into a single hunk
+		last := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			} else if j-last > 2*contextLines {
+				break
+			}
+		}
+
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		stop := last + contextLines + 1
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(posA[start], posA[stop]-posA[start]),
+			hunkRange(posB[start], posB[stop]-posB[start]))
+
+		for _, op := range ops[start:stop] {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.line)
+		}
+
+		i = stop
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the range of a hunk. Line numbers start at 1, an empty
+// range refers to the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// exportLines splits a configuration script into lines, without the
+// header containing the time of the export.
+func exportLines(s string) []string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	s = strings.TrimSuffix(s, "\n")
+	if len(s) == 0 {
+		return []string{}
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > 0 && header.MatchString(lines[0]) {
+		lines = lines[1:]
+	}
+	return lines
+}
+
+type diffOp struct {
+	kind byte // ' ' unchanged, '-' removed, '+' added
+	line string
+}
+
+// diffLines computes the changes between a and b through their longest
+// common subsequence. Since configurations usually differ in a few lines
+// only, the common prefix and suffix are skipped first.
+func diffLines(a, b []string) []diffOp {
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		ops = append(ops, diffOp{' ', l})
+	}
+
+	ma := a[prefix : len(a)-suffix]
+	mb := b[prefix : len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// ma[i:] and mb[j:]
+	lcs := make([][]int32, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			switch {
+			case ma[i] == mb[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			ops = append(ops, diffOp{' ', ma[i]})
+			i++
+			j++
+		case j == len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', ma[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', mb[j]})
+			j++
+		}
+	}
+
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+
+	return ops
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addressListCmd represents the address-list command
+var addressListCmd = &cobra.Command{
+	Use:   "address-list",
+	Short: "Manage the firewall address lists of a microtik router",
+	Long: `Manage the firewall address lists of a microtik router
+
+The entries of the firewall address lists (/ip/firewall/address-list) can
+be listed, added (e.g. to block an abusive ip address) and removed. Entries
+can only be added to or removed from the lists configured in
+'address_lists' in the firewall section of the router (e.g.
+[microtik.firewall]).
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Please select a command (--help for available options)")
+	},
+}
+
+var addressListListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the entries of the firewall address lists",
+	Long: `List the entries of the firewall address lists
+
+Flags: D = dynamic (added with timeout), X = disabled
+
+The result can be optionally written to stdio in JSON. In this case the
+timeout will be returned in nano seconds.
+`,
+	Run: addressListList,
+}
+
+var addressListAddCmd = &cobra.Command{
+	Use:   "add address",
+	Short: "Add an ip address or network to a firewall address list",
+	Long: `Add an ip address or network to a firewall address list
+
+With --timeout the router removes the entry automatically after the
+given duration. Example:
+
+$ infractl address-list add 203.0.113.7 --list blocked --timeout 24h --comment "ssh brute force"
+`,
+	Args: cobra.ExactArgs(1),
+	Run:  addressListAdd,
+}
+
+var addressListRemoveCmd = &cobra.Command{
+	Use:   "remove address",
+	Short: "Remove an ip address or network from a firewall address list",
+	Args:  cobra.ExactArgs(1),
+	Run:   addressListRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(addressListCmd)
+	addressListCmd.AddCommand(addressListListCmd)
+	addressListCmd.AddCommand(addressListAddCmd)
+	addressListCmd.AddCommand(addressListRemoveCmd)
+
+	for _, c := range []*cobra.Command{addressListListCmd, addressListAddCmd, addressListRemoveCmd} {
+		addMicrotikFlags(c)
+	}
+
+	addressListListCmd.Flags().StringP("list", "l", "", "only show the entries of this list")
+	addressListListCmd.Flags().Bool("json", false, "outputs the result as json")
+	addressListAddCmd.Flags().StringP("list", "l", "", "name of the address list")
+	addressListAddCmd.Flags().Duration("timeout", 0, "remove the entry after this duration (0 = never)")
+	addressListAddCmd.Flags().String("comment", "", "comment of the entry")
+	addressListRemoveCmd.Flags().StringP("list", "l", "", "name of the address list")
+}
+
+// newFirewallMicrotik reads the config file and returns a Microtik object
+// with the firewall options of the selected router.
+func newFirewallMicrotik(cmd *cobra.Command, quiet bool) *microtik.Microtik {
+
+	// Try to read config file
+	configFileMsg := ""
+
+	if err := viper.ReadInConfig(); err == nil {
+		configFileMsg = fmt.Sprintf("Using config file: %s", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			configFileMsg = fmt.Sprintf("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if !quiet {
+		fmt.Println(configFileMsg)
+	}
+
+	p := bindMicrotikFlags(cmd)
+
+	return microtik.New(microtikConfig(p), microtikFirewall(p)...)
+}
+
+func addressListList(cmd *cobra.Command, args []string) {
+
+	outputJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	list, err := cmd.Flags().GetString("list")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mt := newFirewallMicrotik(cmd, outputJSON)
+	defer mt.Close()
+
+	entries, err := mt.AddressListEntries(list)
+	if err != nil {
+		routerFatal(err)
+	}
+
+	if outputJSON {
+		j, err := json.Marshal(entries)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(j))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tFLAGS\tLIST\tADDRESS\tTIMEOUT\tCOMMENT")
+	for _, e := range entries {
+		flags := ""
+		if e.Disabled {
+			flags += "X"
+		}
+		if e.Dynamic {
+			flags += "D"
+		}
+		timeout := ""
+		if e.Timeout > 0 {
+			timeout = e.Timeout.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ID, flags, e.List, e.Address, timeout, e.Comment)
+	}
+	tw.Flush()
+}
+
+func addressListAdd(cmd *cobra.Command, args []string) {
+
+	list, _ := cmd.Flags().GetString("list")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	comment, _ := cmd.Flags().GetString("comment")
+
+	if len(list) == 0 {
+		log.Fatal("no address list provided (--list)")
+	}
+
+	mt := newFirewallMicrotik(cmd, false)
+	defer mt.Close()
+
+	if err := mt.AddToAddressList(list, args[0], timeout, comment); err != nil {
+		routerFatal(err)
+	}
+
+	log.Printf("%s added to address list %s\n", args[0], list)
+}
+
+func addressListRemove(cmd *cobra.Command, args []string) {
+
+	list, _ := cmd.Flags().GetString("list")
+
+	if len(list) == 0 {
+		log.Fatal("no address list provided (--list)")
+	}
+
+	mt := newFirewallMicrotik(cmd, false)
+	defer mt.Close()
+
+	if err := mt.RemoveFromAddressList(list, args[0]); err != nil {
+		routerFatal(err)
+	}
+
+	log.Printf("%s removed from address list %s\n", args[0], list)
+}
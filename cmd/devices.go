@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dh1tw/infractl/devices"
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// devicesCmd represents the devices command
+var devicesCmd = &cobra.Command{
+	Use:   "devices",
+	Short: "List the devices in the network of a microtik router",
+	Long: `List the devices in the network of a microtik router
+
+This command will connect to a microtik router and list the devices found
+in the leases of the DHCP server (/ip/dhcp-server/lease) and in the ARP
+table (/ip/arp), identified by their MAC address. A device is online if it
+answers ARP requests or has recently been seen by the DHCP server.
+
+Friendly names can be assigned to the devices in the devices section of
+the router (e.g. [[microtik.devices]]). Devices marked as required are
+flagged as missing when they are offline. In this case the command exits
+with status 1.
+
+Flags: O = online, D = DHCP lease, M = missing
+
+The result can be optionally written to stdio in JSON.
+`,
+	Run: listDevices,
+}
+
+func init() {
+	rootCmd.AddCommand(devicesCmd)
+	addMicrotikFlags(devicesCmd)
+	devicesCmd.Flags().Bool("online", false, "only show the devices which are online")
+	devicesCmd.Flags().Bool("json", false, "outputs the result as json")
+}
+
+func listDevices(cmd *cobra.Command, args []string) {
+
+	// Try to read config file
+	configFileMsg := ""
+
+	if err := viper.ReadInConfig(); err == nil {
+		configFileMsg = fmt.Sprintf("Using config file: %s", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			configFileMsg = fmt.Sprintf("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	p := bindMicrotikFlags(cmd)
+
+	outputJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	onlineOnly, err := cmd.Flags().GetBool("online")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !outputJSON {
+		fmt.Println(configFileMsg)
+	}
+
+	known := knownDevices(p)
+
+	mt := microtik.New(microtikConfig(p))
+	defer mt.Close()
+
+	leases, err := mt.DHCPLeases()
+	if err != nil {
+		routerFatal(err)
+	}
+
+	arp, err := mt.ARP()
+	if err != nil {
+		routerFatal(err)
+	}
+
+	all := devices.Merge(leases, arp, known)
+
+	list := []devices.Device{}
+	missing := []string{}
+	for _, d := range all {
+		if d.Missing {
+			missing = append(missing, d.Name)
+		}
+		if !onlineOnly || d.Online || d.Missing {
+			list = append(list, d)
+		}
+	}
+
+	if outputJSON {
+		j, err := json.Marshal(list)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(j))
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tFLAGS\tADDRESS\tMAC-ADDRESS\tHOST-NAME\tINTERFACE\tLAST-SEEN")
+		for _, d := range list {
+			lastSeen := ""
+			if d.LastSeen > 0 {
+				lastSeen = d.LastSeen.String()
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				d.Name, deviceFlags(d), d.Address, d.MACAddress, d.HostName, d.Interface, lastSeen)
+		}
+		tw.Flush()
+	}
+
+	if len(missing) > 0 {
+		mt.Close()
+		log.Printf("required device(s) missing: %s\n", strings.Join(missing, ", "))
+		os.Exit(1)
+	}
+}
+
+// deviceFlags returns the flags of a device.
+func deviceFlags(d devices.Device) string {
+	flags := ""
+	if d.Online {
+		flags += "O"
+	}
+	if d.DHCP {
+		flags += "D"
+	}
+	if d.Missing {
+		flags += "M"
+	}
+	return flags
+}
+
+// knownDevices returns the devices registered in the devices section of
+// the router (e.g. [[microtik.devices]]).
+func knownDevices(p routerProfile) []devices.Known {
+
+	known := []devices.Known{}
+
+	if err := viper.UnmarshalKey(p.key("devices"), &known); err != nil {
+		log.Fatalf("unable to parse %s: %v", p.key("devices"), err)
+	}
+
+	for _, k := range known {
+		if len(k.MACAddress) == 0 {
+			log.Fatalf("device %s in %s requires a mac address", k.Name, p.key("devices"))
+		}
+	}
+
+	return known
+}
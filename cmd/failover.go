@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/dh1tw/infractl/failover"
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// failoverCmd represents the failover command
+var failoverCmd = &cobra.Command{
+	Use:   "failover",
+	Short: "Automatic failover between a primary (ADSL) and a backup (4G) uplink",
+	Long: `Automatic failover between a primary (ADSL) and a backup (4G) uplink
+
+This command runs as a daemon and continuously pings a list of hosts
+through the uplinks. If the primary uplink fails for a configurable amount
+of consecutive probes, its route on the microtik router will be disabled so
+that the traffic is routed through the backup uplink.
+
+If the interfaces of both uplinks are configured in the [microtik.uplinks]
+section, the router pings through each uplink, including the one on standby.
+The controller then only fails over to a backup uplink which replies and only
+tries the primary uplink again once it replies. Otherwise the pings are sent
+from this host and only the uplink in use can be probed.
+
+After a hold-down time the primary route is re-enabled on trial. If it fails
+again during the trial, the controller falls back immediately and doubles the
+hold-down time. If the backup uplink is dead as well, the 4G modem will be
+power cycled.
+
+The primary and backup routes have to be registered in the config file
+(see [microtik.routes]). The failover parameters are set in the [failover]
+section. The same controller can also be run within 'infractl web' by setting
+'enabled = true' in the [failover] section.
+
+Under Linux, sending pings require elevated privileges (see 'infractl ping').
+
+See the example config file for more details:
+https://github.com/dh1tw/infractl/blob/master/.infractl.toml
+`,
+	Run: runFailover,
+}
+
+func init() {
+	rootCmd.AddCommand(failoverCmd)
+	addMicrotikFlags(failoverCmd)
+	failoverCmd.Flags().String("primary", "adsl", "name of the primary route")
+	failoverCmd.Flags().String("backup", "4g", "name of the backup route")
+}
+
+func runFailover(cmd *cobra.Command, args []string) {
+
+	// Try to read config file
+	if err := viper.ReadInConfig(); err == nil {
+		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			fmt.Println("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// the router can also be selected in the [failover] section
+	if !cmd.Flags().Changed("router") && viper.IsSet("failover.router") {
+		cmd.Flags().Set("router", viper.GetString("failover.router"))
+	}
+
+	p := bindMicrotikFlags(cmd)
+	viper.BindPFlag("failover.primary", cmd.Flags().Lookup("primary"))
+	viper.BindPFlag("failover.backup", cmd.Flags().Lookup("backup"))
+
+	opts, _ := microtikRoutes(p)
+	opts = append(opts, reset4gDuration(p))
+	opts = append(opts, microtik.KeepAlive(viper.GetDuration(p.key("keepalive"))))
+
+	mt := microtik.New(microtikConfig(p), opts...)
+	defer mt.Close()
+
+	fc := newFailover(mt, p)
+	defer fc.Close()
+
+	go fc.Run()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	<-c
+}
+
+// newFailover returns a failover controller configured according to the
+// [failover] section of the config file.
+func newFailover(mt *microtik.Microtik, p routerProfile) *failover.Controller {
+
+	primary := viper.GetString("failover.primary")
+	backup := viper.GetString("failover.backup")
+
+	if len(primary) == 0 || len(backup) == 0 {
+		log.Fatal("failover requires a primary and a backup route")
+	}
+
+	uplinks := viper.GetStringMapString(p.key("uplinks"))
+	opts := []failover.Option{
+		failover.UplinkInterfaces(map[string]string{
+			primary: uplinks[strings.ToLower(primary)],
+			backup:  uplinks[strings.ToLower(backup)],
+		}),
+	}
+
+	if viper.IsSet("failover.hosts") {
+		opts = append(opts, failover.Hosts(viper.GetStringSlice("failover.hosts")))
+	}
+	if viper.IsSet("failover.interval") {
+		opts = append(opts, failover.Interval(viper.GetDuration("failover.interval")))
+	}
+	if viper.IsSet("failover.timeout") {
+		opts = append(opts, failover.PingTimeout(viper.GetDuration("failover.timeout")))
+	}
+	if viper.IsSet("failover.samples") {
+		opts = append(opts, failover.PingSamples(viper.GetInt("failover.samples")))
+	}
+	if viper.IsSet("failover.max_loss") {
+		opts = append(opts, failover.MaxLoss(viper.GetFloat64("failover.max_loss")))
+	}
+	if viper.IsSet("failover.max_rtt") {
+		opts = append(opts, failover.MaxRTT(viper.GetDuration("failover.max_rtt")))
+	}
+	if viper.IsSet("failover.fail_threshold") {
+		opts = append(opts, failover.FailThreshold(viper.GetInt("failover.fail_threshold")))
+	}
+	if viper.IsSet("failover.recover_threshold") {
+		opts = append(opts, failover.RecoverThreshold(viper.GetInt("failover.recover_threshold")))
+	}
+	if viper.IsSet("failover.hold_down") && viper.IsSet("failover.max_hold_down") {
+		opts = append(opts, failover.HoldDown(viper.GetDuration("failover.hold_down"),
+			viper.GetDuration("failover.max_hold_down")))
+	}
+	if viper.IsSet("failover.reset_threshold") && viper.IsSet("failover.reset_hold_down") {
+		opts = append(opts, failover.ResetThreshold(viper.GetInt("failover.reset_threshold"),
+			viper.GetDuration("failover.reset_hold_down")))
+	}
+
+	return failover.New(mt, primary, backup, opts...)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// hashPasswordCmd represents the hash-password command
+var hashPasswordCmd = &cobra.Command{
+	Use:   "hash-password",
+	Short: "Generate a bcrypt hash of a password for the webserver",
+	Long: `Generate a bcrypt hash of a password for the webserver
+
+The webserver doesn't store the passwords of its users in clear text. Instead
+the bcrypt hash generated by this command has to be set as the password of
+the user in the [web.auth] section of the config file.
+
+Example:
+$ infractl hash-password
+Password:
+$2a$10$...
+`,
+	Run: hashPassword,
+}
+
+func init() {
+	rootCmd.AddCommand(hashPasswordCmd)
+	hashPasswordCmd.Flags().IntP("cost", "c", bcrypt.DefaultCost, "bcrypt cost")
+}
+
+func hashPassword(cmd *cobra.Command, args []string) {
+
+	cost, err := cmd.Flags().GetInt("cost")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	pw, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(pw) == 0 {
+		log.Fatal("empty password")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword(pw, cost)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(string(hash))
+}
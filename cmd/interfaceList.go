@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// interfaceListCmd represents the interface-list command
+var interfaceListCmd = &cobra.Command{
+	Use:   "interface-list",
+	Short: "List the interfaces of a microtik router",
+	Long: `List the interfaces of a microtik router
+
+This command will connect to a microtik router and list all interfaces
+(/interface) with their state, link speed (ethernet only) and their
+rx/tx byte and error counters.
+
+With --traffic the current throughput of the running interfaces (or of
+the interfaces selected with --interface) is sampled as well.
+
+Flags: R = running, X = disabled
+
+The result can be optionally written to stdio in JSON.
+`,
+	Run: interfaceList,
+}
+
+func init() {
+	rootCmd.AddCommand(interfaceListCmd)
+	addMicrotikFlags(interfaceListCmd)
+	interfaceListCmd.Flags().Bool("traffic", false, "sample the current throughput")
+	interfaceListCmd.Flags().StringSliceP("interface", "i", []string{}, "only show these interfaces")
+	interfaceListCmd.Flags().Bool("json", false, "outputs the result as json")
+}
+
+func interfaceList(cmd *cobra.Command, args []string) {
+
+	// Try to read config file
+	configFileMsg := ""
+
+	if err := viper.ReadInConfig(); err == nil {
+		configFileMsg = fmt.Sprintf("Using config file: %s", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			configFileMsg = fmt.Sprintf("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	p := bindMicrotikFlags(cmd)
+
+	outputJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sampleTraffic, err := cmd.Flags().GetBool("traffic")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	selected, err := cmd.Flags().GetStringSlice("interface")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !outputJSON {
+		fmt.Println(configFileMsg)
+	}
+
+	mt := microtik.New(microtikConfig(p))
+	defer mt.Close()
+
+	all, err := mt.Interfaces()
+	if err != nil {
+		routerFatal(err)
+	}
+
+	ifaces := []microtik.Interface{}
+	for _, iface := range all {
+		if len(selected) == 0 || contains(selected, iface.Name) {
+			ifaces = append(ifaces, iface)
+		}
+	}
+
+	traffic := make(map[string]microtik.Traffic)
+
+	if sampleTraffic {
+		names := []string{}
+		for _, iface := range ifaces {
+			if iface.Running {
+				names = append(names, iface.Name)
+			}
+		}
+		if len(names) > 0 {
+			res, err := mt.MonitorTraffic(names...)
+			if err != nil {
+				routerFatal(err)
+			}
+			for _, t := range res {
+				traffic[t.Interface] = t
+			}
+		}
+	}
+
+	if outputJSON {
+		res := interfaceListResult{Interfaces: ifaces}
+		if sampleTraffic {
+			res.Traffic = traffic
+		}
+		j, err := json.Marshal(res)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(j))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "NAME\tFLAGS\tTYPE\tMTU\tSPEED\tRX-BYTES\tTX-BYTES\tRX-ERRORS\tTX-ERRORS"
+	if sampleTraffic {
+		header += "\tRX-BPS\tTX-BPS"
+	}
+	fmt.Fprintln(tw, header)
+	for _, i := range ifaces {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%d\t%d\t%d\t%d",
+			i.Name, interfaceFlags(i), i.Type, i.MTU, i.LinkSpeed,
+			i.RxBytes, i.TxBytes, i.RxErrors, i.TxErrors)
+		if sampleTraffic {
+			t := traffic[i.Name]
+			fmt.Fprintf(tw, "\t%d\t%d", t.RxBitsPerSecond, t.TxBitsPerSecond)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
+
+type interfaceListResult struct {
+	Interfaces []microtik.Interface        `json:"interfaces"`
+	Traffic    map[string]microtik.Traffic `json:"traffic,omitempty"`
+}
+
+// interfaceFlags returns the flags of an interface in the notation of the
+// RouterOS terminal.
+func interfaceFlags(i microtik.Interface) string {
+	flags := ""
+	if i.Disabled {
+		flags += "X"
+	}
+	if i.Running {
+		flags += "R"
+	}
+	return flags
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultRouter is the name of the router configured in the [microtik]
+// section.
+const defaultRouter = "default"
+
+// routerProfile identifies the section of the config file which contains
+// the parameters of a microtik router. The router in the [microtik]
+// section is called "default", additional routers are configured in
+// [routers.<name>] sections with the same keys.
+type routerProfile struct {
+	name   string
+	prefix string
+}
+
+// key returns the full key of a parameter of the router.
+func (p routerProfile) key(k string) string {
+	return p.prefix + "." + k
+}
+
+// reset4gKey returns the full key of a parameter of the 4G reset policy.
+// The policy of the default router is configured in the [reset4g] section,
+// while the policies of the other routers are configured in
+// [routers.<name>.reset4g].
+func (p routerProfile) reset4gKey(k string) string {
+	if p.prefix == "microtik" {
+		return "reset4g." + k
+	}
+	return p.key("reset4g." + k)
+}
+
+// routerProfiles returns the profiles of all routers in the config file.
+func routerProfiles() []routerProfile {
+
+	profiles := []routerProfile{}
+
+	if viper.IsSet("microtik") {
+		profiles = append(profiles, routerProfile{defaultRouter, "microtik"})
+	}
+
+	names := []string{}
+	for name := range viper.GetStringMap("routers") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		profiles = append(profiles, routerProfile{name, "routers." + name})
+	}
+
+	return profiles
+}
+
+// selectRouter returns the profile of the router with the given name. If
+// no name is provided, the router in the [microtik] section is selected,
+// or the only router in the [routers] section.
+func selectRouter(name string) routerProfile {
+
+	name = strings.ToLower(name)
+
+	if len(name) > 0 && viper.IsSet("routers."+name) {
+		return routerProfile{name, "routers." + name}
+	}
+
+	if len(name) > 0 && name != defaultRouter {
+		log.Fatalf("unknown router %s (see [routers] section of the config file)", name)
+	}
+
+	if viper.IsSet("microtik") {
+		return routerProfile{defaultRouter, "microtik"}
+	}
+
+	profiles := routerProfiles()
+	switch len(profiles) {
+	case 0:
+		// no config file, only the flags are available
+		return routerProfile{defaultRouter, "microtik"}
+	case 1:
+		return profiles[0]
+	default:
+		log.Fatal("multiple routers configured, select one with --router")
+	}
+
+	return routerProfile{}
+}
+
+// addMicrotikFlags adds the flags for connecting to a microtik router
+// to a command.
+func addMicrotikFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("router", "R", "", "name of the router in the [routers] section of the config file")
+	cmd.Flags().StringP("address", "a", "192.168.0.1", "address of your microtik router")
+	cmd.Flags().IntP("port", "p", 8728, "API port of your microtik router")
+	cmd.Flags().StringP("username", "U", "admin", "username for your microtik router")
+	cmd.Flags().StringP("password", "P", "admin", "password for your microtik router")
+	cmd.Flags().Bool("tls", false, "use the encrypted API service (api-ssl, port 8729)")
+	cmd.Flags().String("tls-ca", "", "CA certificate file to verify the router's certificate")
+	cmd.Flags().String("tls-server-name", "", "name expected in the router's certificate (default: address)")
+	cmd.Flags().Bool("tls-insecure", false, "skip the verification of the router's certificate")
+	cmd.Flags().String("tls-cert", "", "client certificate file")
+	cmd.Flags().String("tls-key", "", "client key file")
+}
+
+// bindMicrotikFlags selects the router profile with the --router flag and
+// binds the flags added by addMicrotikFlags to the keys of its section.
+func bindMicrotikFlags(cmd *cobra.Command) routerProfile {
+
+	name, err := cmd.Flags().GetString("router")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	p := selectRouter(name)
+
+	viper.BindPFlag(p.key("address"), cmd.Flags().Lookup("address"))
+	viper.BindPFlag(p.key("port"), cmd.Flags().Lookup("port"))
+	viper.BindPFlag(p.key("username"), cmd.Flags().Lookup("username"))
+	viper.BindPFlag(p.key("password"), cmd.Flags().Lookup("password"))
+	viper.BindPFlag(p.key("tls"), cmd.Flags().Lookup("tls"))
+	viper.BindPFlag(p.key("tls_ca"), cmd.Flags().Lookup("tls-ca"))
+	viper.BindPFlag(p.key("tls_server_name"), cmd.Flags().Lookup("tls-server-name"))
+	viper.BindPFlag(p.key("tls_insecure"), cmd.Flags().Lookup("tls-insecure"))
+	viper.BindPFlag(p.key("tls_cert"), cmd.Flags().Lookup("tls-cert"))
+	viper.BindPFlag(p.key("tls_key"), cmd.Flags().Lookup("tls-key"))
+
+	return p
+}
+
+// microtikConfig returns the connection parameters of the microtik router
+// from its section of the config file (or the bound pflags).
+func microtikConfig(p routerProfile) microtik.Config {
+
+	c := microtik.Config{
+		Address:            viper.GetString(p.key("address")),
+		Port:               viper.GetInt(p.key("port")),
+		Username:           viper.GetString(p.key("username")),
+		Password:           viper.GetString(p.key("password")),
+		Transport:          viper.GetString(p.key("transport")),
+		TLS:                viper.GetBool(p.key("tls")),
+		CAFile:             viper.GetString(p.key("tls_ca")),
+		ServerName:         viper.GetString(p.key("tls_server_name")),
+		InsecureSkipVerify: viper.GetBool(p.key("tls_insecure")),
+		CertFile:           viper.GetString(p.key("tls_cert")),
+		KeyFile:            viper.GetString(p.key("tls_key")),
+	}
+
+	// use the default port of the api-ssl / www-ssl service unless
+	// a port has been set explicitly
+	if !viper.IsSet(p.key("port")) {
+		switch {
+		case c.Transport == microtik.TransportREST:
+			c.Port = 443
+		case c.TLS:
+			c.Port = 8729
+		}
+	}
+
+	return c
+}
+
+// microtikRoutes reads the routes listed in the routes section of the
+// router (e.g. [microtik.routes]) from the config file and returns the
+// corresponding options for the Microtik constructor (including the
+// whitelist of modifiable attributes) together with the names of the
+// routes. Each route is identified by any combination of the keys comment,
+// comment_regex, dst_address, gateway, routing_mark and id.
+func microtikRoutes(p routerProfile) ([]microtik.Option, []string) {
+
+	opts := []microtik.Option{}
+	routeNames := []string{}
+
+	if viper.IsSet(p.key("routes.attributes")) {
+		opts = append(opts, microtik.RouteAttributes(viper.GetStringSlice(p.key("routes.attributes"))...))
+	}
+
+	routes := viper.GetStringSlice(p.key("routes.routes"))
+
+	for _, r := range routes {
+		rMap := viper.GetStringMapString(r)
+		if len(rMap) == 0 {
+			log.Fatalf("hashmap for route %s not found in config file", r)
+		}
+		name, ok := rMap["name"]
+		if !ok {
+			log.Fatalf("hashmap for route %s missing parameter 'name'", r)
+		}
+
+		sel := microtik.RouteSelector{
+			Comment:     rMap["comment"],
+			DstAddress:  rMap["dst_address"],
+			Gateway:     rMap["gateway"],
+			RoutingMark: rMap["routing_mark"],
+			ID:          rMap["id"],
+		}
+
+		if expr, ok := rMap["comment_regex"]; ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				log.Fatalf("invalid comment_regex for route %s: %v", r, err)
+			}
+			sel.CommentRegex = re
+		}
+
+		if sel.IsEmpty() {
+			log.Fatalf("hashmap for route %s requires at least one of the parameters "+
+				"'comment', 'comment_regex', 'dst_address', 'gateway', 'routing_mark' or 'id'", r)
+		}
+
+		opts = append(opts, microtik.RouteMatch(name, sel))
+		routeNames = append(routeNames, name)
+	}
+
+	return opts, routeNames
+}
+
+// microtikFirewall returns the options for the firewall from the firewall
+// section of the router (e.g. [microtik.firewall]): the address lists which
+// may be modified and the NAT rules (name = comment) which may be enabled
+// or disabled.
+func microtikFirewall(p routerProfile) []microtik.Option {
+
+	opts := []microtik.Option{
+		microtik.AddressList(viper.GetStringSlice(p.key("firewall.address_lists"))...),
+	}
+
+	for name, comment := range viper.GetStringMapString(p.key("firewall.nat")) {
+		opts = append(opts, microtik.NatRuleID(name, comment))
+	}
+
+	return opts
+}
+
+// microtikSafeMode returns the probe and the options for route transactions
+// (safe mode) from the safe_mode section of the router (e.g.
+// [microtik.safe_mode]) in the config file.
+// The probe is nil if no probe host has been configured.
+func microtikSafeMode(p routerProfile) (microtik.Probe, []microtik.TransactionOption) {
+
+	viper.SetDefault(p.key("safe_mode.deadline"), time.Second*30)
+	viper.SetDefault(p.key("safe_mode.interval"), time.Second*5)
+	viper.SetDefault(p.key("safe_mode.timeout"), time.Second*3)
+	viper.SetDefault(p.key("safe_mode.samples"), 3)
+	viper.SetDefault(p.key("safe_mode.max_loss"), 50)
+
+	opts := []microtik.TransactionOption{
+		microtik.ProbeDeadline(viper.GetDuration(p.key("safe_mode.deadline"))),
+		microtik.ProbeInterval(viper.GetDuration(p.key("safe_mode.interval"))),
+	}
+
+	host := viper.GetString(p.key("safe_mode.probe"))
+	if len(host) == 0 {
+		return nil, opts
+	}
+
+	probe := microtik.PingProbe(host,
+		viper.GetDuration(p.key("safe_mode.timeout")),
+		viper.GetInt(p.key("safe_mode.samples")),
+		viper.GetFloat64(p.key("safe_mode.max_loss")))
+
+	return probe, opts
+}
+
+// Exit codes of the commands if a request to the router fails. Any other
+// error results in exit code 1.
+const (
+	exitRouteNotFound = 3
+	exitAuth          = 4
+	exitUnreachable   = 5
+	exitRouterTrap    = 6
+	exitUnknownRoute  = 7
+)
+
+// routerFatal logs an error returned by a microtik router and exits with
+// the exit code matching the error.
+func routerFatal(err error) {
+	log.Println(err)
+
+	switch {
+	case errors.Is(err, microtik.ErrUnknownRoute), errors.Is(err, microtik.ErrUnknownNatRule):
+		os.Exit(exitUnknownRoute)
+	case errors.Is(err, microtik.ErrRouteNotFound), errors.Is(err, microtik.ErrNatRuleNotFound):
+		os.Exit(exitRouteNotFound)
+	case errors.Is(err, microtik.ErrAuth):
+		os.Exit(exitAuth)
+	case errors.Is(err, microtik.ErrUnreachable):
+		os.Exit(exitUnreachable)
+	case errors.Is(err, microtik.ErrRouterTrap):
+		os.Exit(exitRouterTrap)
+	default:
+		os.Exit(1)
+	}
+}
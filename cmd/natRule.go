@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// natRuleCmd represents the nat-rule command
+var natRuleCmd = &cobra.Command{
+	Use:   "nat-rule",
+	Short: "Enable and disable NAT rules of a microtik router",
+	Long: `Enable and disable NAT rules of a microtik router
+
+NAT rules (/ip/firewall/nat), e.g. port forwardings, can be enabled and
+disabled. Similar to the routes, the rules are registered in the config
+file with an arbitrary name and their comment in the firewall section of
+the router (e.g. [microtik.firewall.nat]). Only registered rules can be
+modified. Example:
+
+[microtik.firewall.nat]
+remote = "port forwarding to the remote station"
+
+$ infractl nat-rule disable remote
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Please select a command (--help for available options)")
+	},
+}
+
+var natRuleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the registered NAT rules",
+	Long: `List the registered NAT rules
+
+Flags: X = disabled
+
+The result can be optionally written to stdio in JSON.
+`,
+	Run: natRuleList,
+}
+
+var natRuleEnableCmd = &cobra.Command{
+	Use:   "enable name",
+	Short: "Enable a registered NAT rule",
+	Args:  cobra.ExactArgs(1),
+	Run:   natRuleSet,
+}
+
+var natRuleDisableCmd = &cobra.Command{
+	Use:   "disable name",
+	Short: "Disable a registered NAT rule",
+	Args:  cobra.ExactArgs(1),
+	Run:   natRuleSet,
+}
+
+func init() {
+	rootCmd.AddCommand(natRuleCmd)
+	natRuleCmd.AddCommand(natRuleListCmd)
+	natRuleCmd.AddCommand(natRuleEnableCmd)
+	natRuleCmd.AddCommand(natRuleDisableCmd)
+
+	for _, c := range []*cobra.Command{natRuleListCmd, natRuleEnableCmd, natRuleDisableCmd} {
+		addMicrotikFlags(c)
+	}
+
+	natRuleListCmd.Flags().Bool("json", false, "outputs the result as json")
+}
+
+func natRuleList(cmd *cobra.Command, args []string) {
+
+	outputJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mt := newFirewallMicrotik(cmd, outputJSON)
+	defer mt.Close()
+
+	rules, err := mt.NatRules()
+	if err != nil {
+		routerFatal(err)
+	}
+
+	if outputJSON {
+		j, err := json.Marshal(rules)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(j))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tFLAGS\tCHAIN\tACTION\tPROTOCOL\tDST-PORT\tTO-ADDRESSES\tTO-PORTS\tCOMMENT")
+	for _, r := range rules {
+		flags := ""
+		if r.Disabled {
+			flags = "X"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Name, flags, r.Chain, r.Action, r.Protocol, r.DstPort,
+			r.ToAddresses, r.ToPorts, r.Comment)
+	}
+	tw.Flush()
+}
+
+func natRuleSet(cmd *cobra.Command, args []string) {
+
+	mt := newFirewallMicrotik(cmd, false)
+	defer mt.Close()
+
+	var err error
+	if cmd.Name() == "enable" {
+		err = mt.EnableNatRule(args[0])
+	} else {
+		err = mt.DisableNatRule(args[0])
+	}
+	if err != nil {
+		routerFatal(err)
+	}
+
+	log.Printf("nat rule %s %sd\n", args[0], cmd.Name())
+}
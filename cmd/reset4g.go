@@ -5,8 +5,10 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/dh1tw/infractl/microtik"
+	"github.com/dh1tw/infractl/reset"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -17,20 +19,35 @@ var reset4gCmd = &cobra.Command{
 	Short: "Hard power reset of a 4G USB modem connected to the microtik router",
 	Long: `This command performs a hard power reset of a 4G stick connected
 to the internal USB port of a microtik routerboard. The power will be cut for
-5 seconds.
+5 seconds (see 'power_off' in the [reset4g] section of the config file).
+
+The [reset4g] section also defines which routes will be enabled or disabled
+before the reset (by default none) and which routes will be restored to their
+previous state afterwards.
+
+If checks are configured, the command waits until the modem has recovered
+and reports the progress through the following phases:
+
+  powering_off        the power of the USB port is cut
+  enumerating_usb     the modem shows up again on the USB bus
+  registering_lte     the modem registers to the LTE network
+  connecting          the PPP / DHCP connection is established
+  verifying_internet  the probe host is reachable through the LTE interface
+
+The phases are checked through the LTE interface of the router and / or
+the status (ppp_status, network_type) of the mf823 modem. The command fails
+if the modem hasn't recovered within the configured time.
 
 You can save the details of your microtik router in the config file under the
-the key [microtik].
+the key [microtik]. Use --tls to connect through the encrypted API service
+(api-ssl).
 `,
 	Run: reset4g,
 }
 
 func init() {
 	rootCmd.AddCommand(reset4gCmd)
-	reset4gCmd.Flags().StringP("address", "a", "192.168.0.1", "address of your microtik router")
-	reset4gCmd.Flags().IntP("port", "p", 8728, "API port of your microtik router")
-	reset4gCmd.Flags().StringP("username", "U", "admin", "username for your microtik router")
-	reset4gCmd.Flags().StringP("password", "P", "admin", "password for your microtik router")
+	addMicrotikFlags(reset4gCmd)
 
 }
 
@@ -48,30 +65,87 @@ func reset4g(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	viper.BindPFlag("microtik.address", cmd.Flags().Lookup("address"))
-	viper.BindPFlag("microtik.port", cmd.Flags().Lookup("port"))
-	viper.BindPFlag("microtik.username", cmd.Flags().Lookup("username"))
-	viper.BindPFlag("microtik.password", cmd.Flags().Lookup("password"))
+	p := bindMicrotikFlags(cmd)
 
-	mConfig := microtik.Config{
-		Address:  viper.GetString("microtik.address"),
-		Port:     viper.GetInt("microtik.port"),
-		Username: viper.GetString("microtik.username"),
-		Password: viper.GetString("microtik.password"),
-	}
+	opts, _ := microtikRoutes(p)
+	opts = append(opts, reset4gDuration(p))
+
+	mt := microtik.New(microtikConfig(p), opts...)
+	defer mt.Close()
 
-	mt := microtik.New(mConfig)
+	policy := reset4gPolicy(p, mt, reset.Progress(func(job reset.Job) {
+		log.Println("4G reset:", job.Phase)
+	}))
 
-	// before we can reset the 4G modem, we must make sure that the ADSL route
-	// is active. Otherwise, when the 4G route would become unavailable after the reset
-	// and no other route is available, microtik generates a new dynamical route which
-	// messes up the configuration.
-	if err := mt.SetRoute("adsl", "disabled=false"); err != nil {
-		log.Fatal(err)
+	if _, err := policy.Run(); err != nil {
+		routerFatal(err)
 	}
 
-	if err := mt.Reset4G(); err != nil {
-		log.Fatal(err)
+	if policy.Waits() {
+		log.Println("4G modem reset successfully")
+		return
 	}
 	log.Println("4G reset successfully initiated")
 }
+
+// reset4gDuration returns the option for the USB power-off duration from
+// the reset4g section of the router in the config file.
+func reset4gDuration(p routerProfile) microtik.Option {
+	viper.SetDefault(p.reset4gKey("power_off"), time.Second*5)
+	return microtik.ResetDuration(viper.GetDuration(p.reset4gKey("power_off")))
+}
+
+// reset4gPolicy returns the policy for resetting the 4G modem from the
+// reset4g section of the router in the config file ([reset4g] for the
+// default router, [routers.<name>.reset4g] otherwise).
+func reset4gPolicy(p routerProfile, mt *microtik.Microtik, opts ...reset.Option) *reset.Policy {
+
+	viper.SetDefault(p.reset4gKey("power_off"), time.Second*5)
+	viper.SetDefault(p.reset4gKey("wait"), time.Minute*3)
+	viper.SetDefault(p.reset4gKey("interval"), time.Second*5)
+	viper.SetDefault(p.reset4gKey("lte_interface"), "lte1")
+	viper.SetDefault(p.reset4gKey("probe_timeout"), time.Second*3)
+	viper.SetDefault(p.reset4gKey("probe_samples"), 3)
+
+	// before we can reset the 4G modem, we must make sure that another route
+	// (e.g. ADSL) is active. Otherwise, when the 4G route would become unavailable
+	// after the reset and no other route is available, microtik generates a new
+	// dynamical route which messes up the configuration. Since the routes differ
+	// between the routers, they have to be set in the config file ('enable').
+	opts = append(opts,
+		reset.EnableRoutes(viper.GetStringSlice(p.reset4gKey("enable"))...),
+		reset.DisableRoutes(viper.GetStringSlice(p.reset4gKey("disable"))...),
+		reset.RestoreRoutes(viper.GetStringSlice(p.reset4gKey("restore"))...),
+		reset.PowerOff(viper.GetDuration(p.reset4gKey("power_off"))),
+		reset.PollInterval(viper.GetDuration(p.reset4gKey("interval"))),
+	)
+
+	checks := viper.GetStringSlice(p.reset4gKey("check"))
+
+	for _, c := range checks {
+		switch c {
+		case "mf823":
+			if !viper.IsSet("mf823.address") {
+				log.Fatalf("%s 'mf823' requires mf823.address", p.reset4gKey("check"))
+			}
+			opts = append(opts, reset.Monitor(reset.MF823Checks(viper.GetString("mf823.address"))))
+		case "router":
+			opts = append(opts, reset.Monitor(reset.RouterChecks(mt, viper.GetString(p.reset4gKey("lte_interface")))))
+		default:
+			log.Fatalf("invalid %s '%s' (mf823, router)", p.reset4gKey("check"), c)
+		}
+	}
+
+	if probe := viper.GetString(p.reset4gKey("probe")); len(probe) > 0 {
+		opts = append(opts, reset.Monitor(reset.PingCheck(mt,
+			viper.GetString(p.reset4gKey("lte_interface")), probe,
+			viper.GetDuration(p.reset4gKey("probe_timeout")),
+			viper.GetInt(p.reset4gKey("probe_samples")))))
+	}
+
+	if len(checks) > 0 || viper.IsSet(p.reset4gKey("probe")) {
+		opts = append(opts, reset.Wait(viper.GetDuration(p.reset4gKey("wait"))))
+	}
+
+	return reset.New(mt, opts...)
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// routeListCmd represents the route-list command
+var routeListCmd = &cobra.Command{
+	Use:   "route-list",
+	Short: "List all routes of a microtik router",
+	Long: `List all routes of a microtik router
+
+This command will connect to a microtik router and list all routes (ip/route)
+with their attributes, including the dynamic ones. In contrast to
+route-status, the routes don't have to be registered in the config file.
+This helps to find the comments which have to be put into the config file.
+
+Flags: A = active, X = disabled, D = dynamic
+
+The result can be optionally written to stdio in JSON.
+`,
+	Run: routeList,
+}
+
+func init() {
+	rootCmd.AddCommand(routeListCmd)
+	addMicrotikFlags(routeListCmd)
+	routeListCmd.Flags().Bool("json", false, "outputs the result as json")
+}
+
+func routeList(cmd *cobra.Command, args []string) {
+
+	// Try to read config file
+	configFileMsg := ""
+
+	if err := viper.ReadInConfig(); err == nil {
+		configFileMsg = fmt.Sprintf("Using config file: %s", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			configFileMsg = fmt.Sprintf("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	p := bindMicrotikFlags(cmd)
+
+	outputJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !outputJSON {
+		fmt.Println(configFileMsg)
+	}
+
+	mt := microtik.New(microtikConfig(p))
+	defer mt.Close()
+
+	routes, err := mt.Routes()
+	if err != nil {
+		routerFatal(err)
+	}
+
+	if outputJSON {
+		j, err := json.Marshal(routes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(j))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tFLAGS\tDST-ADDRESS\tGATEWAY\tDISTANCE\tROUTING-MARK\tCOMMENT")
+	for _, r := range routes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			r.ID, routeFlags(r), r.DstAddress, r.Gateway, r.Distance, r.RoutingMark, r.Comment)
+	}
+	tw.Flush()
+}
+
+// routeFlags returns the flags of a route in the notation of the RouterOS
+// terminal.
+func routeFlags(r microtik.Route) string {
+	flags := ""
+	if r.Disabled {
+		flags += "X"
+	}
+	if r.Active {
+		flags += "A"
+	}
+	if r.Dynamic {
+		flags += "D"
+	}
+	return flags
+}
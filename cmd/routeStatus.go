@@ -40,10 +40,10 @@ var routeStatusCmd = &cobra.Command{
 
 This command will connect to a microtik router and retrieve the status if
 the requested route (ip/route) is disabled and/or active. Since the routes
-don't have static labels, the most reliable way to find the route is by
-it's comment field. Therefore a shorthand identified / name (string) and the
-corresponding comment (string) has to be set in the config file when calling
-this command.
+don't have static labels, a shorthand / name (string) and a selector have to
+be set in the config file when calling this command. The selector can match
+the route's comment (exact or regular expression), destination address,
+gateway, routing mark and id. Exactly one route must match the selector.
 
 See the example config file for more details:
 https://github.com/dh1tw/infractl/blob/master/.infractl.toml
@@ -55,10 +55,7 @@ https://github.com/dh1tw/infractl/blob/master/.infractl.toml
 
 func init() {
 	rootCmd.AddCommand(routeStatusCmd)
-	routeStatusCmd.Flags().StringP("address", "a", "192.168.0.1", "address of your microtik router")
-	routeStatusCmd.Flags().IntP("port", "p", 8728, "API port of your microtik router")
-	routeStatusCmd.Flags().StringP("username", "U", "admin", "username for your microtik router")
-	routeStatusCmd.Flags().StringP("password", "P", "admin", "password for your microtik router")
+	addMicrotikFlags(routeStatusCmd)
 	routeStatusCmd.Flags().Bool("json", false, "outputs the result as json")
 }
 
@@ -79,64 +76,33 @@ func routeStatus(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	viper.BindPFlag("microtik.address", cmd.Flags().Lookup("address"))
-	viper.BindPFlag("microtik.port", cmd.Flags().Lookup("port"))
-	viper.BindPFlag("microtik.username", cmd.Flags().Lookup("username"))
-	viper.BindPFlag("microtik.password", cmd.Flags().Lookup("password"))
-	viper.BindPFlag("microtik.routes.json", cmd.Flags().Lookup("json"))
+	p := bindMicrotikFlags(cmd)
+	viper.BindPFlag(p.key("routes.json"), cmd.Flags().Lookup("json"))
 
-	outputJSON := viper.GetBool("microtik.routes.json")
+	outputJSON := viper.GetBool(p.key("routes.json"))
 
 	if !outputJSON {
 		fmt.Println(configFileMsg)
 	}
 
-	mConfig := microtik.Config{
-		Address:  viper.GetString("microtik.address"),
-		Port:     viper.GetInt("microtik.port"),
-		Username: viper.GetString("microtik.username"),
-		Password: viper.GetString("microtik.password"),
+	if !viper.IsSet(p.key("routes.routes")) {
+		log.Fatalf("key %s not found in config file", p.key("routes"))
 	}
 
-	if !viper.IsSet("microtik.routes.routes") {
-		log.Fatal("key microtik.routes not found in config file")
+	opts, routeNames := microtikRoutes(p)
+	if len(routeNames) == 0 {
+		log.Fatalf("key routes in %s is missing or empty", p.key("routes"))
 	}
 
-	routes := viper.GetStringSlice("microtik.routes.routes")
-	if len(routes) == 0 {
-		log.Fatal("key routes in microtik.routes is missing or empty")
-	}
-
-	opts := []microtik.Option{}
-	routeNames := []string{}
-
-	for _, r := range routes {
-		rMap := viper.GetStringMapString(r)
-		if len(rMap) == 0 {
-			log.Fatalf("hashmap for route %s not found in config file", r)
-		}
-		name, ok := rMap["name"]
-		if !ok {
-			log.Fatalf("hashmap for route %s missing parameter 'name'", r)
-		}
-		comment, ok := rMap["comment"]
-		if !ok {
-			log.Fatalf("hashmap for route %s missing parameter 'comment'", r)
-		}
-
-		opt := microtik.RouteID(name, comment)
-		opts = append(opts, opt)
-		routeNames = append(routeNames, name)
-	}
-
-	mt := microtik.New(mConfig, opts...)
+	mt := microtik.New(microtikConfig(p), opts...)
+	defer mt.Close()
 
 	results := make(routeStatusResults)
 
 	for _, r := range routeNames {
 		res, err := mt.RouteStatus(r)
 		if err != nil {
-			log.Fatal(err)
+			routerFatal(err)
 		}
 		results[r] = res
 	}
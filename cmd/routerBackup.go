@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dh1tw/infractl/backup"
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// routerBackupCmd represents the router-backup command
+var routerBackupCmd = &cobra.Command{
+	Use:   "router-backup",
+	Short: "Backup the configuration of a microtik router",
+	Long: `Backup the configuration of a microtik router
+
+This command will connect to a microtik router, export its configuration
+as script (/export) and store it in a timestamped directory within the
+backup directory (one subdirectory per router). With --system-backup a
+binary backup (/system/backup/save) is downloaded as well. Only the latest
+backups are kept (see 'keep' in the [backup] section of the config file).
+
+Use 'infractl router-diff' to compare the backups with each other or with
+the live configuration of the router.
+
+Downloading the files requires RouterOS v7.13 or newer. Older versions
+only provide the contents of small files (< 4kB).
+
+Note that the binary backup is not encrypted and contains the passwords
+of the router.
+`,
+	Run: routerBackup,
+}
+
+func init() {
+	rootCmd.AddCommand(routerBackupCmd)
+	addMicrotikFlags(routerBackupCmd)
+	routerBackupCmd.Flags().String("dir", "backups", "directory in which the backups are stored")
+	routerBackupCmd.Flags().Int("keep", 10, "amount of backups to keep (0 = all)")
+	routerBackupCmd.Flags().Bool("system-backup", false, "download a binary backup as well")
+}
+
+func routerBackup(cmd *cobra.Command, args []string) {
+
+	// Try to read config file
+	if err := viper.ReadInConfig(); err == nil {
+		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			fmt.Println("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	p := bindMicrotikFlags(cmd)
+	viper.BindPFlag("backup.directory", cmd.Flags().Lookup("dir"))
+	viper.BindPFlag("backup.keep", cmd.Flags().Lookup("keep"))
+	viper.BindPFlag("backup.system_backup", cmd.Flags().Lookup("system-backup"))
+
+	mt := microtik.New(microtikConfig(p))
+	defer mt.Close()
+
+	export, err := mt.Export()
+	if err != nil {
+		routerFatal(fmt.Errorf("unable to export configuration: %w", err))
+	}
+
+	var sysBackup []byte
+	if viper.GetBool("backup.system_backup") {
+		sysBackup, err = mt.Backup()
+		if err != nil {
+			routerFatal(fmt.Errorf("unable to download backup: %w", err))
+		}
+	}
+
+	snap, err := backupStore(p).Save(export, sysBackup)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("configuration of router %s saved in %s\n", p.name, snap.Dir)
+}
+
+// backupStore returns the store for the backups of the router, located
+// in a subdirectory of the backup directory.
+func backupStore(p routerProfile) *backup.Store {
+	viper.SetDefault("backup.directory", "backups")
+	viper.SetDefault("backup.keep", 10)
+	dir := filepath.Join(viper.GetString("backup.directory"), p.name)
+	return backup.NewStore(dir, viper.GetInt("backup.keep"))
+}
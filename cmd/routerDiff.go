@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dh1tw/infractl/backup"
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// routerDiffCmd represents the router-diff command
+var routerDiffCmd = &cobra.Command{
+	Use:   "router-diff [from] [to]",
+	Short: "Show the changes of the configuration of a microtik router",
+	Long: `Show the changes of the configuration of a microtik router
+
+This command shows the unified diff between two configuration exports.
+An export can be referenced by the name of a backup (e.g. 20200102-150405,
+see --list), by a file name or by 'live' for the current configuration of
+the router.
+
+Without arguments, the latest backup is compared with the live
+configuration. With a single argument, the given export is compared with
+the live configuration. Examples:
+
+$ infractl router-diff
+$ infractl router-diff 20200102-150405
+$ infractl router-diff 20200102-150405 20200103-150405
+`,
+	Args: cobra.MaximumNArgs(2),
+	Run:  routerDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(routerDiffCmd)
+	addMicrotikFlags(routerDiffCmd)
+	routerDiffCmd.Flags().String("dir", "backups", "directory in which the backups are stored")
+	routerDiffCmd.Flags().Bool("list", false, "list the stored backups")
+}
+
+func routerDiff(cmd *cobra.Command, args []string) {
+
+	// Try to read config file
+	configFileMsg := ""
+
+	if err := viper.ReadInConfig(); err == nil {
+		configFileMsg = fmt.Sprintf("Using config file: %s", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			configFileMsg = fmt.Sprintf("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	p := bindMicrotikFlags(cmd)
+	viper.BindPFlag("backup.directory", cmd.Flags().Lookup("dir"))
+
+	list, err := cmd.Flags().GetBool("list")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the diff is written to stdout
+	fmt.Fprintln(os.Stderr, configFileMsg)
+
+	store := backupStore(p)
+
+	if list {
+		snaps, err := store.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tTIME\tSYSTEM-BACKUP")
+		for _, s := range snaps {
+			fmt.Fprintf(tw, "%s\t%s\t%t\n", s.Name, s.Time.Format("2006-01-02 15:04:05"), s.HasBackup)
+		}
+		tw.Flush()
+		return
+	}
+
+	refs := []string{"", "live"}
+	copy(refs, args)
+
+	if len(refs[0]) == 0 {
+		latest, err := store.Latest()
+		if err != nil {
+			log.Fatal(err)
+		}
+		refs[0] = latest.Name
+	}
+
+	mt := microtik.New(microtikConfig(p))
+	defer mt.Close()
+
+	exports := make([]string, 2)
+	for i, ref := range refs {
+		exports[i], err = loadExport(mt, store, ref)
+		if err != nil {
+			routerFatal(err)
+		}
+	}
+
+	diff := backup.Diff(exports[0], exports[1], refs[0], refs[1])
+	if len(diff) == 0 {
+		fmt.Fprintln(os.Stderr, "no changes")
+		return
+	}
+	fmt.Print(diff)
+}
+
+// loadExport returns the configuration script referenced by the name of
+// a backup, a file name or 'live'.
+func loadExport(mt *microtik.Microtik, store *backup.Store, ref string) (string, error) {
+
+	if ref == "live" {
+		return mt.Export()
+	}
+
+	if snap, err := store.Get(ref); err == nil {
+		return snap.Export()
+	}
+
+	data, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("%s is neither a backup nor a readable file", ref)
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dh1tw/infractl/connectivity"
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// routerPingCmd represents the router-ping command
+var routerPingCmd = &cobra.Command{
+	Use:   "router-ping host",
+	Short: "Ping a host from the microtik router",
+	Long: `Ping a host from the microtik router
+
+In contrast to 'infractl ping', the pings are sent by the microtik router.
+By selecting the outgoing interface, the source address or the routing table,
+each uplink can be tested independently, including the one which is currently
+on standby. The interfaces of the uplinks are configured in the
+[microtik.uplinks] section and can be selected with --uplink. Example:
+
+$ infractl router-ping google.com --uplink adsl --uplink 4g
+
+With --traceroute the path to the host is determined instead.
+
+The result can be optionally written to stdio in JSON. In this case the
+round trip times will be returned in nano seconds.
+`,
+	Args: cobra.ExactArgs(1),
+	Run:  routerPing,
+}
+
+func init() {
+	rootCmd.AddCommand(routerPingCmd)
+	addMicrotikFlags(routerPingCmd)
+	routerPingCmd.Flags().StringSlice("uplink", []string{}, "ping through the interface of these uplinks")
+	routerPingCmd.Flags().StringP("interface", "i", "", "outgoing interface")
+	routerPingCmd.Flags().String("src-address", "", "source address")
+	routerPingCmd.Flags().String("routing-table", "", "routing table")
+	routerPingCmd.Flags().IntP("count", "c", 0, "amount of pings (default 3) or probes per hop (default 1)")
+	routerPingCmd.Flags().Duration("interval", time.Second, "interval between two pings")
+	routerPingCmd.Flags().Bool("traceroute", false, "determine the path to the host")
+	routerPingCmd.Flags().Int("max-hops", 30, "maximum amount of hops of the traceroute")
+	routerPingCmd.Flags().Bool("json", false, "outputs the result as json")
+}
+
+func routerPing(cmd *cobra.Command, args []string) {
+
+	// Try to read config file
+	configFileMsg := ""
+
+	if err := viper.ReadInConfig(); err == nil {
+		configFileMsg = fmt.Sprintf("Using config file: %s", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			configFileMsg = fmt.Sprintf("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	p := bindMicrotikFlags(cmd)
+
+	outputJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	traceroute, err := cmd.Flags().GetBool("traceroute")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	uplinks, err := cmd.Flags().GetStringSlice("uplink")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := microtik.PingOptions{}
+	opts.Interface, _ = cmd.Flags().GetString("interface")
+	opts.SrcAddress, _ = cmd.Flags().GetString("src-address")
+	opts.RoutingTable, _ = cmd.Flags().GetString("routing-table")
+	opts.Count, _ = cmd.Flags().GetInt("count")
+	opts.Interval, _ = cmd.Flags().GetDuration("interval")
+	opts.MaxHops, _ = cmd.Flags().GetInt("max-hops")
+
+	if len(uplinks) > 0 && len(opts.Interface) > 0 {
+		log.Fatal("--uplink and --interface are mutually exclusive")
+	}
+
+	if traceroute && len(uplinks) > 1 {
+		log.Fatal("--traceroute supports only a single uplink")
+	}
+
+	// resolve the interfaces of the uplinks
+	configured := viper.GetStringMapString(p.key("uplinks"))
+	paths := []routerPingPath{}
+	for _, uplink := range uplinks {
+		iface, ok := configured[strings.ToLower(uplink)]
+		if !ok {
+			log.Fatalf("unknown uplink %s (see [%s.uplinks])", uplink, p.prefix)
+		}
+		paths = append(paths, routerPingPath{uplink: uplink, iface: iface})
+	}
+	if len(paths) == 0 {
+		paths = append(paths, routerPingPath{iface: opts.Interface})
+	}
+
+	if !outputJSON {
+		fmt.Println(configFileMsg)
+	}
+
+	mt := microtik.New(microtikConfig(p))
+	defer mt.Close()
+
+	host := args[0]
+
+	if traceroute {
+		opts.Interface = paths[0].iface
+		hops, err := mt.Traceroute(host, opts)
+		if err != nil {
+			routerFatal(err)
+		}
+		if outputJSON {
+			j, err := json.Marshal(hops)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Print(string(j))
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "#\tADDRESS\tLOSS\tSENT\tLAST\tAVG\tBEST\tWORST\tSTATUS")
+		for _, h := range hops {
+			fmt.Fprintf(tw, "%d\t%s\t%.0f%%\t%d\t%v\t%v\t%v\t%v\t%s\n",
+				h.Hop, h.Address, h.Loss, h.Sent, h.Last, h.RTT, h.MinRTT, h.MaxRTT, h.Status)
+		}
+		tw.Flush()
+		return
+	}
+
+	// results are indexed by the uplink (or the host if no uplink
+	// has been selected)
+	results := make(map[string]connectivity.PingResult)
+
+	for _, path := range paths {
+		opts.Interface = path.iface
+		res, err := mt.Ping(host, opts)
+		if err != nil {
+			routerFatal(err)
+		}
+		key := path.uplink
+		if len(key) == 0 {
+			key = host
+		}
+		results[key] = res
+	}
+
+	if outputJSON {
+		j, err := json.Marshal(results)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(j))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UPLINK\tINTERFACE\tHOST\tLOSS\tMIN\tAVG\tMAX")
+	for _, path := range paths {
+		key := path.uplink
+		if len(key) == 0 {
+			key = host
+		}
+		r := results[key]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.0f%%\t%v\t%v\t%v\n",
+			path.uplink, path.iface, r.Address, r.Loss, r.MinRTT, r.RTT, r.MaxRTT)
+	}
+	tw.Flush()
+}
+
+// routerPingPath is the uplink (and its interface) through which the
+// router sends the pings.
+type routerPingPath struct {
+	uplink string
+	iface  string
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// routerRebootCmd represents the router-reboot command
+var routerRebootCmd = &cobra.Command{
+	Use:   "router-reboot",
+	Short: "Reboot a microtik router",
+	Long: `Reboot a microtik router
+
+This command reboots a microtik router (/system/reboot) and waits until its
+API is reachable again. Afterwards the time during which the router was
+down is reported. The command fails if the router isn't reachable again
+within the timeout.
+
+The reboot has to be confirmed by typing 'yes', unless --yes is set.
+
+WARNING:
+If the router doesn't come back, somebody has to drive to the site!
+`,
+	Run: routerReboot,
+}
+
+func init() {
+	rootCmd.AddCommand(routerRebootCmd)
+	addMicrotikFlags(routerRebootCmd)
+	routerRebootCmd.Flags().BoolP("yes", "y", false, "reboot without confirmation")
+	routerRebootCmd.Flags().Duration("timeout", time.Minute*5, "maximum time to wait for the router")
+}
+
+func routerReboot(cmd *cobra.Command, args []string) {
+
+	// Try to read config file
+	if err := viper.ReadInConfig(); err == nil {
+		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			fmt.Println("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	p := bindMicrotikFlags(cmd)
+	viper.BindPFlag(p.key("reboot_timeout"), cmd.Flags().Lookup("timeout"))
+
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	config := microtikConfig(p)
+
+	if !yes {
+		fmt.Printf("Reboot router %s (%s)? Type 'yes' to confirm: ", p.name, config.Address)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != "yes" {
+			log.Fatal("reboot aborted")
+		}
+	}
+
+	mt := microtik.New(config)
+	defer mt.Close()
+
+	log.Printf("rebooting router %s\n", p.name)
+
+	res, err := mt.Reboot(viper.GetDuration(p.key("reboot_timeout")))
+	if err != nil {
+		routerFatal(err)
+	}
+
+	log.Printf("router %s is back after %v (down for %v)\n", p.name,
+		res.Up.Sub(res.Started).Round(time.Second), res.Downtime.Round(time.Second))
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// routerStatusCmd represents the router-status command
+var routerStatusCmd = &cobra.Command{
+	Use:   "router-status",
+	Short: "Show the system health of a microtik router",
+	Long: `Show the system health of a microtik router
+
+This command will connect to a microtik router and retrieve its system
+resources (uptime, cpu load, memory, disk) and the readings of its sensors
+(voltage, temperature...). The available sensors depend on the routerboard.
+
+The result can be optionally written to stdio in JSON.
+`,
+	Run: routerStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(routerStatusCmd)
+	addMicrotikFlags(routerStatusCmd)
+	routerStatusCmd.Flags().Bool("json", false, "outputs the result as json")
+}
+
+func routerStatus(cmd *cobra.Command, args []string) {
+
+	// Try to read config file
+	configFileMsg := ""
+
+	if err := viper.ReadInConfig(); err == nil {
+		configFileMsg = fmt.Sprintf("Using config file: %s", viper.ConfigFileUsed())
+	} else {
+		if strings.Contains(err.Error(), "Not Found in") {
+			configFileMsg = fmt.Sprintf("no config file found")
+		} else {
+			fmt.Println("Error parsing config file", viper.ConfigFileUsed())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	p := bindMicrotikFlags(cmd)
+
+	outputJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !outputJSON {
+		fmt.Println(configFileMsg)
+	}
+
+	mt := microtik.New(microtikConfig(p))
+	defer mt.Close()
+
+	res, err := mt.SystemResource()
+	if err != nil {
+		routerFatal(err)
+	}
+
+	health, err := mt.Health()
+	if err != nil {
+		routerFatal(err)
+	}
+
+	if outputJSON {
+		j, err := json.Marshal(routerStatusResult{res, health})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(j))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "board:\t%s (%s)\n", res.BoardName, res.Architecture)
+	fmt.Fprintf(tw, "version:\t%s\n", res.Version)
+	fmt.Fprintf(tw, "uptime:\t%v\n", res.Uptime.Round(time.Second))
+	fmt.Fprintf(tw, "cpu:\t%s, %d core(s), %d MHz\n", res.CPU, res.CPUCount, res.CPUFrequency)
+	fmt.Fprintf(tw, "cpu load:\t%d%%\n", res.CPULoad)
+	fmt.Fprintf(tw, "memory:\t%s free of %s\n", mib(res.FreeMemory), mib(res.TotalMemory))
+	fmt.Fprintf(tw, "disk:\t%s free of %s\n", mib(res.FreeHDDSpace), mib(res.TotalHDDSpace))
+
+	sensors := []string{}
+	for name := range health.Sensors {
+		sensors = append(sensors, name)
+	}
+	sort.Strings(sensors)
+
+	for _, name := range sensors {
+		fmt.Fprintf(tw, "%s:\t%s\n", name, health.Sensors[name])
+	}
+	tw.Flush()
+}
+
+type routerStatusResult struct {
+	Resource microtik.SystemResource `json:"resource"`
+	Health   microtik.Health         `json:"health"`
+}
+
+func mib(bytes uint64) string {
+	return fmt.Sprintf("%.1f MiB", float64(bytes)/1024/1024)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/dh1tw/infractl/microtik"
 	"github.com/spf13/cobra"
@@ -14,35 +15,54 @@ import (
 // setRouteCmd represents the setRoute command
 var setRouteCmd = &cobra.Command{
 	Use:   "set-route",
-	Short: "set a parameter for a route of a microtik router",
-	Long: `set a parameter for a route of a microtik router
+	Short: "set parameters of a route of a microtik router",
+	Long: `set parameters of a route of a microtik router
 
-This command will connect to a microtik router and execute the set command
-on a particular route (ip/route). Since the routes don't have static labels,
-the routes are identified by their comment fields. Therefore a shorthand / name
-(string) and the corresponding comment (string) has to be set in the config file
-when calling this command.
+This command will connect to a microtik router and modify one or more
+parameters of a particular route (ip/route) in a single step. Afterwards the
+route is read back in order to verify the modification. Since the routes
+don't have static labels, a shorthand / name (string) and a selector have to
+be set in the config file when calling this command. The selector can match
+the route's comment (exact or regular expression), destination address,
+gateway, routing mark and id. Exactly one route must match the selector.
 
 See the example config file for more details:
 https://github.com/dh1tw/infractl/blob/master/.infractl.toml
 
+Only the parameters listed in 'attributes' in the routes section of the
+router (e.g. [microtik.routes]) can be modified. By default only enabling
+and disabling routes is allowed.
+
+With --safe the modification is executed in safe mode: after applying it,
+the probe host configured in the safe_mode section of the router is pinged
+until it replies. If it doesn't reply within the deadline, the original
+parameters of the route are restored automatically.
+
 WARNING:
-Executing the wrong commands (e.g. disable=yes) might lock you out!
+Disabling the wrong route might lock you out! Use --safe and backup the
+configuration with 'infractl router-backup' beforehand.
 
-Example:
-./infractl set-route --config=.myconfig.toml -r adsl -c "disabled=false"
+Examples:
+./infractl set-route --config=.myconfig.toml -r adsl --enable
+./infractl set-route --config=.myconfig.toml -r adsl --disable --safe
+./infractl set-route --config=.myconfig.toml -r 4g --distance 2 --check-gateway ping
 	`,
 	Run: setRoute,
 }
 
 func init() {
 	rootCmd.AddCommand(setRouteCmd)
-	setRouteCmd.Flags().StringP("address", "a", "192.168.0.1", "address of your microtik router")
-	setRouteCmd.Flags().IntP("port", "p", 8728, "API port of your microtik router")
-	setRouteCmd.Flags().StringP("username", "U", "admin", "username for your microtik router")
-	setRouteCmd.Flags().StringP("password", "P", "admin", "password for your microtik router")
+	addMicrotikFlags(setRouteCmd)
 	setRouteCmd.Flags().StringP("route", "r", "adsl", "route name (route must be in config file")
-	setRouteCmd.Flags().StringP("command", "c", "disabled=false", "command")
+	setRouteCmd.Flags().Bool("enable", false, "enable the route")
+	setRouteCmd.Flags().Bool("disable", false, "disable the route")
+	setRouteCmd.Flags().Int("distance", 0, "set the distance of the route (1-255)")
+	setRouteCmd.Flags().String("gateway", "", "set the gateway of the route")
+	setRouteCmd.Flags().String("check-gateway", "", "set the gateway check method (arp, ping, bfd, none)")
+	setRouteCmd.Flags().String("comment", "", "set the comment of the route")
+	setRouteCmd.Flags().Bool("safe", false, "restore the route if the probe host is unreachable afterwards")
+	setRouteCmd.Flags().String("probe", "", "probe host for --safe (default: safe_mode.probe of the router)")
+	setRouteCmd.Flags().Duration("deadline", time.Second*30, "time within which the probe host must reply (--safe)")
 }
 
 func setRoute(cmd *cobra.Command, args []string) {
@@ -61,10 +81,9 @@ func setRoute(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	viper.BindPFlag("microtik.address", cmd.Flags().Lookup("address"))
-	viper.BindPFlag("microtik.port", cmd.Flags().Lookup("port"))
-	viper.BindPFlag("microtik.username", cmd.Flags().Lookup("username"))
-	viper.BindPFlag("microtik.password", cmd.Flags().Lookup("password"))
+	p := bindMicrotikFlags(cmd)
+	viper.BindPFlag(p.key("safe_mode.probe"), cmd.Flags().Lookup("probe"))
+	viper.BindPFlag(p.key("safe_mode.deadline"), cmd.Flags().Lookup("deadline"))
 
 	fmt.Println(configFileMsg)
 
@@ -73,53 +92,90 @@ func setRoute(cmd *cobra.Command, args []string) {
 		log.Fatal(err)
 	}
 
-	command, err := cmd.Flags().GetString("command")
+	ops, err := routeOps(cmd)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	mConfig := microtik.Config{
-		Address:  viper.GetString("microtik.address"),
-		Port:     viper.GetInt("microtik.port"),
-		Username: viper.GetString("microtik.username"),
-		Password: viper.GetString("microtik.password"),
+	if !viper.IsSet(p.key("routes.routes")) {
+		log.Fatalf("key %s not found in config file", p.key("routes"))
 	}
 
-	if !viper.IsSet("microtik.routes.routes") {
-		log.Fatal("key microtik.routes not found in config file")
+	opts, routeNames := microtikRoutes(p)
+	if len(routeNames) == 0 {
+		log.Fatalf("key routes in %s is missing or empty", p.key("routes"))
 	}
 
-	routes := viper.GetStringSlice("microtik.routes.routes")
-	if len(routes) == 0 {
-		log.Fatal("key routes in microtik.routes is missing or empty")
-	}
+	mt := microtik.New(microtikConfig(p), opts...)
+	defer mt.Close()
 
-	opts := []microtik.Option{}
-	routeNames := []string{}
+	safe, err := cmd.Flags().GetBool("safe")
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	for _, r := range routes {
-		rMap := viper.GetStringMapString(r)
-		if len(rMap) == 0 {
-			log.Fatalf("hashmap for route %s not found in config file", r)
-		}
-		name, ok := rMap["name"]
-		if !ok {
-			log.Fatalf("hashmap for route %s missing parameter 'name'", r)
-		}
-		comment, ok := rMap["comment"]
-		if !ok {
-			log.Fatalf("hashmap for route %s missing parameter 'comment'", r)
+	if !safe {
+		err = mt.UpdateRoute(route, ops...)
+		if err != nil {
+			routerFatal(err)
 		}
+		return
+	}
 
-		opt := microtik.RouteID(name, comment)
-		opts = append(opts, opt)
-		routeNames = append(routeNames, name)
+	probe, txOpts := microtikSafeMode(p)
+	if probe == nil {
+		log.Fatalf("--safe requires a probe host (--probe or %s)", p.key("safe_mode.probe"))
 	}
 
-	mt := microtik.New(mConfig, opts...)
+	change := microtik.RouteChange{Route: route, Ops: ops}
 
-	err = mt.SetRoute(route, command)
+	res, err := mt.Transaction(probe, []microtik.RouteChange{change}, txOpts...)
 	if err != nil {
-		log.Fatal(err)
+		routerFatal(err)
+	}
+
+	fmt.Printf("route %s modified, connectivity verified after %d probe(s)\n", route, res.Probes)
+}
+
+// routeOps returns the modifications of a route requested through the
+// command line flags.
+func routeOps(cmd *cobra.Command) ([]microtik.RouteOp, error) {
+
+	ops := []microtik.RouteOp{}
+	flags := cmd.Flags()
+
+	enable, _ := flags.GetBool("enable")
+	disable, _ := flags.GetBool("disable")
+
+	if enable && disable {
+		return nil, fmt.Errorf("--enable and --disable are mutually exclusive")
+	}
+	if enable {
+		ops = append(ops, microtik.Enable())
+	}
+	if disable {
+		ops = append(ops, microtik.Disable())
+	}
+	if flags.Changed("distance") {
+		distance, _ := flags.GetInt("distance")
+		ops = append(ops, microtik.SetDistance(distance))
 	}
+	if flags.Changed("gateway") {
+		gateway, _ := flags.GetString("gateway")
+		ops = append(ops, microtik.SetGateway(gateway))
+	}
+	if flags.Changed("check-gateway") {
+		method, _ := flags.GetString("check-gateway")
+		ops = append(ops, microtik.SetCheckGateway(method))
+	}
+	if flags.Changed("comment") {
+		comment, _ := flags.GetString("comment")
+		ops = append(ops, microtik.SetComment(comment))
+	}
+
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("no modification requested (see --help)")
+	}
+
+	return ops, nil
 }
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/dh1tw/infractl/mf823"
+	"github.com/dh1tw/infractl/microtik"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -15,15 +16,22 @@ import (
 // status4gCmd represents the status4g command
 var status4gCmd = &cobra.Command{
 	Use:   "4g-status",
-	Short: "Request the status from a ZTE MF823 4G USB Modem",
-	Long: `Request the status from a ZTE MF823 4G USB Modem
+	Short: "Request the status from a 4G modem",
+	Long: `Request the status from a 4G modem
 
-The status of a MF823 can be queried through a REST interface. The list
-of possible parameters is pretty long. The example config file provided
-with the source code (https://github.com/dh1tw/infractl/.infractl.toml)
-should be complete.
+The status can be retrieved from two sources:
+
+mf823 (default): The status of a ZTE MF823 4G USB Modem is queried through
+its REST interface. The list of possible parameters is pretty long. The
+example config file provided with the source code
+(https://github.com/dh1tw/infractl/.infractl.toml) should be complete.
 However a list of parameters has to be supplied when calling this command.
 
+router: The radio and registration status (RSRP, RSRQ, SINR, operator, cell
+id, band...) of the LTE interface is retrieved from the microtik router.
+This works with any modem supported by RouterOS. The router is selected
+with --router (see [routers] section of the config file).
+
 The result can be optionally written to stdio in JSON.
 `,
 	Run: status4g,
@@ -33,6 +41,9 @@ func init() {
 	rootCmd.AddCommand(status4gCmd)
 	status4gCmd.Flags().String("address", "192.168.3.1", "address of the ZTE MF823 4G Stick")
 	status4gCmd.Flags().StringSlice("parameters", []string{"network_type", "network_provider", "signalbar"}, "list of status parameters")
+	status4gCmd.Flags().String("source", "mf823", "source of the status (mf823, router)")
+	status4gCmd.Flags().String("interface", "lte1", "LTE interface of the router (source router)")
+	status4gCmd.Flags().StringP("router", "R", "", "name of the router in the [routers] section of the config file (source router)")
 	status4gCmd.Flags().Bool("json", false, "outputs the result as json")
 }
 
@@ -56,6 +67,17 @@ func status4g(cmd *cobra.Command, args []string) {
 	viper.BindPFlag("mf823.address", cmd.Flags().Lookup("address"))
 	viper.BindPFlag("mf823.parameters", cmd.Flags().Lookup("parameters"))
 	viper.BindPFlag("mf823.json", cmd.Flags().Lookup("json"))
+	viper.BindPFlag("lte.source", cmd.Flags().Lookup("source"))
+	viper.BindPFlag("lte.interface", cmd.Flags().Lookup("interface"))
+
+	switch viper.GetString("lte.source") {
+	case "mf823":
+	case "router":
+		status4gRouter(cmd)
+		return
+	default:
+		log.Fatalf("invalid source %s (mf823, router)", viper.GetString("lte.source"))
+	}
 
 	address := viper.GetString("mf823.address")
 	params := viper.GetStringSlice("mf823.parameters")
@@ -89,3 +111,53 @@ func status4g(cmd *cobra.Command, args []string) {
 		fmt.Printf("%s: %v\n", k, v)
 	}
 }
+
+// status4gRouter retrieves the status of the LTE interface from the
+// microtik router.
+func status4gRouter(cmd *cobra.Command) {
+
+	name, err := cmd.Flags().GetString("router")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	p := selectRouter(name)
+	iface := viper.GetString("lte.interface")
+	outputJSON := viper.GetBool("mf823.json")
+
+	mt := microtik.New(microtikConfig(p))
+	defer mt.Close()
+
+	info, err := mt.LTEInfo(iface)
+	if err != nil {
+		routerFatal(err)
+	}
+
+	if outputJSON {
+		j, err := json.Marshal(info)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(j))
+		return
+	}
+
+	fmt.Printf("Status %s (router %s):\n", iface, p.name)
+	fmt.Printf("registration status: %s\n", info.RegistrationStatus)
+	fmt.Printf("operator: %s\n", info.Operator)
+	fmt.Printf("access technology: %s\n", info.AccessTechnology)
+	fmt.Printf("cell id: %s\n", info.CellID)
+	fmt.Printf("band: %s\n", info.Band)
+	fmt.Printf("rssi: %s dBm\n", levelString(info.RSSI))
+	fmt.Printf("rsrp: %s dBm\n", levelString(info.RSRP))
+	fmt.Printf("rsrq: %s dB\n", levelString(info.RSRQ))
+	fmt.Printf("sinr: %s dB\n", levelString(info.SINR))
+	fmt.Printf("session uptime: %v\n", info.SessionUptime)
+}
+
+func levelString(l *float64) string {
+	if l == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f", *l)
+}
@@ -7,8 +7,10 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"time"
 
 	webserver "github.com/dh1tw/infractl/app"
+	"github.com/dh1tw/infractl/failover"
 	"github.com/dh1tw/infractl/microtik"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -20,13 +22,32 @@ import (
 // webServerCmd represents the web command
 var webServerCmd = &cobra.Command{
 	Use:   "web",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+	Short: "Webserver providing a web interface and a REST API",
+	Long: `Webserver providing a web interface and a REST API
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+The webserver serves the infractl web interface and exposes the status of
+the microtik routes, the 4G modem, the connectivity checks and the systemd
+services through a REST API.
+
+All routers configured in the [microtik] section (router "default") and in
+the [routers.<name>] sections are accessible under
+/api/v1.0/routers/<name>/... (e.g. /api/v1.0/routers/tower/routes). The
+paths without a router name (e.g. /api/v1.0/routes) address the router set
+with 'router' in the [web] section, or else the first router.
+
+Access to the API can be restricted in the [web.auth] section of the config
+file. Clients can authenticate with static API tokens
+("Authorization: Bearer <token>"), with HTTP basic authentication (bcrypt
+password hashes, see 'infractl hash-password') or with TLS client
+certificates. Each client is assigned one of the following roles:
+
+  viewer:   read the status of routes, the 4G modem, pings and services
+  operator: additionally switch routes (also in safe mode), reset the 4G
+            modem and start or restart services
+  admin:    additionally stop services
+
+If no credentials are configured, the API is accessible for everybody.
+`,
 	Run: webServer,
 }
 
@@ -34,6 +55,10 @@ func init() {
 	rootCmd.AddCommand(webServerCmd)
 	webServerCmd.Flags().StringP("address", "w", "127.0.0.1", "address of the webserver (use '0.0.0.0' to listen on all network adapters)")
 	webServerCmd.Flags().IntP("port", "k", 6556, "webserver http port")
+	webServerCmd.Flags().String("tls-cert", "", "TLS certificate file (enables HTTPS, generated if missing)")
+	webServerCmd.Flags().String("tls-key", "", "TLS key file (enables HTTPS, generated if missing)")
+	webServerCmd.Flags().Int("redirect-port", 0, "port on which HTTP requests are redirected to HTTPS (disabled if 0)")
+	webServerCmd.Flags().String("pprof", "", "address for the profiling endpoint, e.g. 'localhost:6060' (disabled if empty)")
 }
 
 func webServer(cmd *cobra.Command, args []string) {
@@ -51,58 +76,96 @@ func webServer(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// Profiling (uncomment if needed)
-	go func() {
-		log.Println(http.ListenAndServe("0.0.0.0:6060", http.DefaultServeMux))
-	}()
-
 	viper.BindPFlag("web.address", cmd.Flags().Lookup("address"))
 	viper.BindPFlag("web.port", cmd.Flags().Lookup("port"))
+	viper.BindPFlag("web.pprof", cmd.Flags().Lookup("pprof"))
+	viper.BindPFlag("web.tls_cert", cmd.Flags().Lookup("tls-cert"))
+	viper.BindPFlag("web.tls_key", cmd.Flags().Lookup("tls-key"))
+	viper.BindPFlag("web.redirect_port", cmd.Flags().Lookup("redirect-port"))
+
+	// Profiling
+	if pprofAddr := viper.GetString("web.pprof"); len(pprofAddr) > 0 {
+		go func() {
+			log.Println(http.ListenAndServe(pprofAddr, http.DefaultServeMux))
+		}()
+	}
 	errorCh := make(chan struct{})
 
 	addr := webserver.Address(viper.GetString("web.address"))
 	port := webserver.Port(viper.GetInt("web.port"))
 
 	opts := []webserver.Option{addr, port, webserver.ErrorCh(errorCh)}
+	opts = append(opts, webAuthOptions()...)
+
+	var mts []*microtik.Microtik
+	var fc *failover.Controller
+
+	failoverRouter := strings.ToLower(viper.GetString("failover.router"))
+
+	viper.SetDefault("lte.interface", "lte1")
 
-	if viper.IsSet("microtik.address") &&
-		viper.IsSet("microtik.port") &&
-		viper.IsSet("microtik.username") &&
-		viper.IsSet("microtik.password") {
+	for _, p := range routerProfiles() {
 
-		mtConfig := microtik.Config{
-			Address:  viper.GetString("microtik.address"),
-			Port:     viper.GetInt("microtik.port"),
-			Username: viper.GetString("microtik.username"),
-			Password: viper.GetString("microtik.password"),
+		if !viper.IsSet(p.key("address")) ||
+			!viper.IsSet(p.key("port")) ||
+			!viper.IsSet(p.key("username")) ||
+			!viper.IsSet(p.key("password")) {
+			continue
 		}
 
-		mtOpts := []microtik.Option{}
-
-		if viper.IsSet("microtik.routes.routes") {
-			routes := viper.GetStringSlice("microtik.routes.routes")
-			for _, r := range routes {
-				rMap := viper.GetStringMapString(r)
-				if len(rMap) == 0 {
-					log.Fatalf("hashmap for route %s not found in config file", r)
-				}
-				name, ok := rMap["name"]
-				if !ok {
-					log.Fatalf("hashmap for route %s missing parameter 'name'", r)
-				}
-				comment, ok := rMap["comment"]
-				if !ok {
-					log.Fatalf("hashmap for route %s missing parameter 'comment'", r)
-				}
-
-				mtOpt := microtik.RouteID(name, comment)
-				mtOpts = append(mtOpts, mtOpt)
-				opts = append(opts, webserver.Route(name))
-			}
+		viper.SetDefault(p.key("keepalive"), time.Second*30)
+
+		mtOpts, routeNames := microtikRoutes(p)
+		mtOpts = append(mtOpts, microtik.KeepAlive(viper.GetDuration(p.key("keepalive"))))
+		mtOpts = append(mtOpts, reset4gDuration(p))
+		mtOpts = append(mtOpts, microtikFirewall(p)...)
+
+		mt := microtik.New(microtikConfig(p), mtOpts...)
+		mts = append(mts, mt)
+
+		r := webserver.Router{
+			Microtik: mt,
+			Routes:   routeNames,
+			Reset4G:  reset4gPolicy(p, mt),
+			Uplinks:  viper.GetStringMapString(p.key("uplinks")),
+			Backups:  backupStore(p),
+			Devices:  knownDevices(p),
+		}
+
+		viper.SetDefault(p.key("reboot_timeout"), time.Minute*5)
+		r.RebootTimeout = viper.GetDuration(p.key("reboot_timeout"))
+
+		r.LTEInterface = viper.GetString("lte.interface")
+		if viper.IsSet(p.key("lte_interface")) {
+			r.LTEInterface = viper.GetString(p.key("lte_interface"))
+		}
+		r.Probe, r.TxOptions = microtikSafeMode(p)
+
+		opts = append(opts, webserver.AddRouter(p.name, r))
+
+		// the failover controller runs on the selected router or else
+		// on the first one
+		if viper.GetBool("failover.enabled") && fc == nil &&
+			(len(failoverRouter) == 0 || failoverRouter == p.name) {
+			fc = newFailover(mt, p)
+			go fc.Run()
+			opts = append(opts, webserver.Failover(fc))
 		}
+	}
+
+	if viper.IsSet("web.router") {
+		opts = append(opts, webserver.DefaultRouter(viper.GetString("web.router")))
+	}
+
+	// changes of the routes and interfaces are pushed to the web interface
+	viper.SetDefault("web.events", true)
+	viper.SetDefault("web.event_history", 100)
+	if viper.GetBool("web.events") {
+		opts = append(opts, webserver.Events(viper.GetInt("web.event_history")))
+	}
 
-		mt := webserver.Microtik(microtik.New(mtConfig, mtOpts...))
-		opts = append(opts, mt)
+	if viper.IsSet("lte.source") {
+		opts = append(opts, webserver.LTESource(viper.GetString("lte.source")))
 	}
 
 	if viper.IsSet("mf823.address") &&
@@ -119,13 +182,35 @@ func webServer(cmd *cobra.Command, args []string) {
 		opts = append(opts, pingEnabled, pingInterval)
 	}
 
+	if viper.IsSet("ping.timeout") {
+		opts = append(opts, webserver.PingTimeout(viper.GetDuration("ping.timeout")))
+	}
+
+	if viper.IsSet("ping.samples") {
+		opts = append(opts, webserver.PingSamples(viper.GetInt("ping.samples")))
+	}
+
+	if viper.IsSet("ping.history") {
+		opts = append(opts, webserver.PingHistory(viper.GetInt("ping.history")))
+	}
+
 	services := viper.GetStringSlice("systemd.services")
 	for _, s := range services {
 		service := webserver.Service(s)
 		opts = append(opts, service)
 	}
 
-	opts = append(opts, webserver.PingAddress(viper.GetStringSlice("ping.address")))
+	pingAddresses := viper.GetStringSlice("ping.address")
+
+	// the hosts assigned to an uplink are always pinged
+	for uplink, host := range viper.GetStringMapString("ping.uplinks") {
+		opts = append(opts, webserver.PingUplink(uplink, host))
+		if !contains(pingAddresses, host) {
+			pingAddresses = append(pingAddresses, host)
+		}
+	}
+
+	opts = append(opts, webserver.PingAddress(pingAddresses))
 
 	webserver := webserver.New(opts...)
 
@@ -140,4 +225,107 @@ func webServer(cmd *cobra.Command, args []string) {
 	case <-c:
 	}
 
+	if fc != nil {
+		fc.Close()
+	}
+
+	for _, mt := range mts {
+		mt.Close()
+	}
+
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// webAuthOptions returns the webserver options for authentication, CORS and
+// TLS from the [web] section of the config file (or the bound pflags).
+func webAuthOptions() []webserver.Option {
+
+	opts := []webserver.Option{}
+
+	if viper.IsSet("web.cors_origins") {
+		opts = append(opts, webserver.CORSOrigins(viper.GetStringSlice("web.cors_origins")))
+	}
+
+	tlsCert := viper.GetString("web.tls_cert")
+	tlsKey := viper.GetString("web.tls_key")
+
+	if len(tlsCert) > 0 || len(tlsKey) > 0 {
+		if len(tlsCert) == 0 || len(tlsKey) == 0 {
+			log.Fatal("HTTPS requires both web.tls_cert and web.tls_key")
+		}
+		opts = append(opts, webserver.TLS(tlsCert, tlsKey))
+		opts = append(opts, webserver.TLSHosts(viper.GetStringSlice("web.tls_hosts")))
+		opts = append(opts, webserver.HTTPRedirectPort(viper.GetInt("web.redirect_port")))
+	}
+
+	if viper.IsSet("web.client_ca") {
+		opts = append(opts, webserver.ClientCA(viper.GetString("web.client_ca")))
+	}
+
+	var tokens []struct {
+		Name  string
+		Token string
+		Role  string
+	}
+	if err := viper.UnmarshalKey("web.auth.tokens", &tokens); err != nil {
+		log.Fatalf("unable to parse web.auth.tokens: %v", err)
+	}
+
+	if len(tokens) > 0 {
+		ta := webserver.NewTokenAuth()
+		for _, t := range tokens {
+			if len(t.Token) == 0 {
+				log.Fatalf("empty token for %s in web.auth.tokens", t.Name)
+			}
+			ta.Add(t.Name, t.Token, parseRole(t.Role))
+		}
+		opts = append(opts, webserver.Authentication(ta))
+	}
+
+	var users []struct {
+		Name     string
+		Password string
+		Role     string
+	}
+	if err := viper.UnmarshalKey("web.auth.users", &users); err != nil {
+		log.Fatalf("unable to parse web.auth.users: %v", err)
+	}
+
+	if len(users) > 0 {
+		ba := webserver.NewBasicAuth()
+		for _, u := range users {
+			ba.Add(u.Name, u.Password, parseRole(u.Role))
+		}
+		opts = append(opts, webserver.Authentication(ba))
+	}
+
+	clients := viper.GetStringMapString("web.auth.clients")
+	if len(clients) > 0 {
+		if !viper.IsSet("web.client_ca") {
+			log.Fatal("web.auth.clients requires web.client_ca")
+		}
+		ca := webserver.NewClientCertAuth()
+		for cn, role := range clients {
+			ca.Add(cn, parseRole(role))
+		}
+		opts = append(opts, webserver.Authentication(ca))
+	}
+
+	return opts
+}
+
+func parseRole(name string) webserver.Role {
+	role, err := webserver.ParseRole(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return role
 }
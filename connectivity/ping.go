@@ -15,7 +15,10 @@ type PingResults map[string]PingResult
 // PingResult is a struct containing the result of a ping to one particular host
 type PingResult struct {
 	Address string        `json:"address"`
-	RTT     time.Duration `json:"rtt"`
+	RTT     time.Duration `json:"rtt"` // average round trip time
+	MinRTT  time.Duration `json:"min_rtt"`
+	MaxRTT  time.Duration `json:"max_rtt"`
+	Loss    float64       `json:"loss"` // packet loss in percent
 	Failed  bool          `json:"failed"`
 }
 
@@ -25,11 +28,11 @@ type PingResult struct {
 // In order to execute this command you might need elevated privileges on Linux.
 // See: https://github.com/sparrc/go-ping for more details.
 func PingHost(address string, timeout time.Duration, samples int) (PingResult, error) {
-	timeoutC := time.NewTimer(timeout).C
 
 	pr := PingResult{
 		Address: address,
 		RTT:     0,
+		Loss:    100,
 		Failed:  true,
 	}
 
@@ -39,21 +42,34 @@ func PingHost(address string, timeout time.Duration, samples int) (PingResult, e
 	}
 
 	pinger.SetPrivileged(true)
+	pinger.Count = samples
+	pinger.Timeout = timeout
 
-	result := make(chan (*goping.Statistics))
+	// buffered, so that the go routine can always terminate
+	result := make(chan (*goping.Statistics), 1)
 
 	go func() {
-		pinger.Count = samples
-		pinger.Run() // blocks until finished
+		pinger.Run() // blocks until finished or timed out
 		result <- pinger.Statistics()
 	}()
 
+	// the pinger stops by itself after the timeout; the timer only
+	// guards against a pinger which doesn't return
+	timer := time.NewTimer(timeout + time.Second)
+	defer timer.Stop()
+
 	select {
-	case <-timeoutC:
+	case <-timer.C:
 		pinger.Stop()
 		return pr, fmt.Errorf("no reply received from %s after %v", address, timeout)
 	case s := <-result:
+		if s.PacketsRecv == 0 {
+			return pr, fmt.Errorf("no reply received from %s after %v", address, timeout)
+		}
 		pr.RTT = s.AvgRtt
+		pr.MinRTT = s.MinRtt
+		pr.MaxRTT = s.MaxRtt
+		pr.Loss = s.PacketLoss
 		pr.Failed = false
 	}
 
@@ -0,0 +1,126 @@
+package devices
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+)
+
+// onlineTimeout is the maximum time since a client with a bound DHCP
+// lease has last been seen by the router in order to be considered online.
+const onlineTimeout = time.Minute * 5
+
+// Known is a device which has been registered in the config file with a
+// friendly name.
+type Known struct {
+	Name       string `mapstructure:"name"`
+	MACAddress string `mapstructure:"mac"`
+	// Required devices should always be present
+	Required bool `mapstructure:"required"`
+}
+
+// Device is a device in the network of the router, detected through the
+// DHCP leases and the ARP table.
+type Device struct {
+	Name       string `json:"name"`
+	MACAddress string `json:"mac_address"`
+	Address    string `json:"address"`
+	HostName   string `json:"host_name"`
+	Interface  string `json:"interface"`
+	Online     bool   `json:"online"`
+	// DHCP is true if the device has a bound DHCP lease
+	DHCP bool `json:"dhcp"`
+	// LastSeen is the time since the device has last been seen by the
+	// DHCP server (0 if unknown)
+	LastSeen time.Duration `json:"last_seen"`
+	Required bool          `json:"required"`
+	// Missing is true if a required device is offline
+	Missing bool `json:"missing"`
+}
+
+// Merge combines the DHCP leases and the ARP entries of a router into a
+// list of devices, identified by their MAC address (case insensitive). A
+// device is online if it answers ARP requests or has recently been seen by
+// the DHCP server. Known devices are always listed, even if the router
+// doesn't know them (anymore). The devices are sorted by their name,
+// unnamed devices by their address.
+func Merge(leases []microtik.DHCPLease, arp []microtik.ARPEntry, known []Known) []Device {
+
+	devices := make(map[string]*Device)
+
+	get := func(mac string) *Device {
+		mac = strings.ToUpper(mac)
+		d, ok := devices[mac]
+		if !ok {
+			d = &Device{MACAddress: mac}
+			devices[mac] = d
+		}
+		return d
+	}
+
+	for _, l := range leases {
+		if len(l.MACAddress) == 0 || l.Disabled || !l.Bound() {
+			continue
+		}
+		d := get(l.MACAddress)
+		d.Address = l.Address
+		d.HostName = l.HostName
+		d.DHCP = true
+		d.LastSeen = l.LastSeen
+		if l.LastSeen > 0 && l.LastSeen < onlineTimeout {
+			d.Online = true
+		}
+	}
+
+	for _, e := range arp {
+		if len(e.MACAddress) == 0 || e.Disabled {
+			continue
+		}
+		d := get(e.MACAddress)
+		if len(d.Address) == 0 {
+			d.Address = e.Address
+		}
+		d.Interface = e.Interface
+		if e.Complete {
+			d.Online = true
+		}
+	}
+
+	for _, k := range known {
+		d := get(k.MACAddress)
+		d.Name = k.Name
+		d.Required = k.Required
+	}
+
+	res := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		d.Missing = d.Required && !d.Online
+		res = append(res, *d)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if (len(res[i].Name) > 0) != (len(res[j].Name) > 0) {
+			return len(res[i].Name) > 0
+		}
+		if res[i].Name != res[j].Name {
+			return res[i].Name < res[j].Name
+		}
+		return lessAddress(res[i].Address, res[j].Address)
+	})
+
+	return res
+}
+
+// lessAddress compares two IP addresses numerically (e.g. 10.0.0.2 before
+// 10.0.0.10). Invalid addresses are compared as strings.
+func lessAddress(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return a < b
+	}
+	return bytes.Compare(ipA.To16(), ipB.To16()) < 0
+}
@@ -0,0 +1,104 @@
+package devices
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+)
+
+func TestMerge(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		leases []microtik.DHCPLease
+		arp    []microtik.ARPEntry
+		known  []Known
+		exp    []Device
+	}{
+		{
+			name: "lease and arp entry of the same device",
+			leases: []microtik.DHCPLease{
+				{Address: "10.0.0.2", MACAddress: "aa:bb:cc:00:00:01", HostName: "laptop", Status: "bound", LastSeen: time.Minute * 10},
+			},
+			arp: []microtik.ARPEntry{
+				{Address: "10.0.0.2", MACAddress: "AA:BB:CC:00:00:01", Interface: "bridge", Complete: true},
+			},
+			exp: []Device{
+				{MACAddress: "AA:BB:CC:00:00:01", Address: "10.0.0.2", HostName: "laptop", Interface: "bridge",
+					Online: true, DHCP: true, LastSeen: time.Minute * 10},
+			},
+		},
+		{
+			name: "lease and arp entry of different devices",
+			leases: []microtik.DHCPLease{
+				{Address: "10.0.0.2", MACAddress: "AA:BB:CC:00:00:01", Status: "bound", LastSeen: time.Second * 30},
+			},
+			arp: []microtik.ARPEntry{
+				{Address: "10.0.0.3", MACAddress: "AA:BB:CC:00:00:02", Interface: "bridge"},
+			},
+			exp: []Device{
+				{MACAddress: "AA:BB:CC:00:00:01", Address: "10.0.0.2", Online: true, DHCP: true, LastSeen: time.Second * 30},
+				{MACAddress: "AA:BB:CC:00:00:02", Address: "10.0.0.3", Interface: "bridge"},
+			},
+		},
+		{
+			name: "disabled and unbound leases and arp entries",
+			leases: []microtik.DHCPLease{
+				{Address: "10.0.0.2", MACAddress: "AA:BB:CC:00:00:01", Status: "bound", Disabled: true},
+				{Address: "10.0.0.3", MACAddress: "AA:BB:CC:00:00:02", Status: "waiting"},
+				{Address: "10.0.0.4", Status: "bound"},
+			},
+			arp: []microtik.ARPEntry{
+				{Address: "10.0.0.5", MACAddress: "AA:BB:CC:00:00:03", Complete: true, Disabled: true},
+				{Address: "10.0.0.6", Complete: true},
+			},
+			exp: []Device{},
+		},
+		{
+			name: "known devices",
+			arp: []microtik.ARPEntry{
+				{Address: "10.0.0.2", MACAddress: "AA:BB:CC:00:00:01", Complete: true},
+				{Address: "10.0.0.3", MACAddress: "AA:BB:CC:00:00:02"},
+			},
+			known: []Known{
+				{Name: "switch", MACAddress: "aa:bb:cc:00:00:01", Required: true},
+				{Name: "camera", MACAddress: "aa:bb:cc:00:00:02", Required: true},
+				{Name: "printer", MACAddress: "aa:bb:cc:00:00:03"},
+				{Name: "nas", MACAddress: "aa:bb:cc:00:00:04", Required: true},
+			},
+			exp: []Device{
+				{Name: "camera", MACAddress: "AA:BB:CC:00:00:02", Address: "10.0.0.3", Required: true, Missing: true},
+				{Name: "nas", MACAddress: "AA:BB:CC:00:00:04", Required: true, Missing: true},
+				{Name: "printer", MACAddress: "AA:BB:CC:00:00:03"},
+				{Name: "switch", MACAddress: "AA:BB:CC:00:00:01", Address: "10.0.0.2", Online: true, Required: true},
+			},
+		},
+		{
+			name: "named devices first, then by address",
+			arp: []microtik.ARPEntry{
+				{Address: "10.0.0.10", MACAddress: "AA:BB:CC:00:00:01"},
+				{Address: "10.0.0.9", MACAddress: "AA:BB:CC:00:00:02"},
+				{Address: "10.0.0.20", MACAddress: "AA:BB:CC:00:00:03"},
+			},
+			known: []Known{
+				{Name: "router", MACAddress: "AA:BB:CC:00:00:03"},
+			},
+			exp: []Device{
+				{Name: "router", MACAddress: "AA:BB:CC:00:00:03", Address: "10.0.0.20"},
+				{MACAddress: "AA:BB:CC:00:00:02", Address: "10.0.0.9"},
+				{MACAddress: "AA:BB:CC:00:00:01", Address: "10.0.0.10"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res := Merge(tc.leases, tc.arp, tc.known)
+			if !reflect.DeepEqual(res, tc.exp) {
+				t.Errorf("got devices\n%+v\nexpected\n%+v", res, tc.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package failover
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dh1tw/infractl/connectivity"
+	"github.com/dh1tw/infractl/microtik"
+)
+
+// Router is the subset of the microtik.Microtik methods which are needed
+// by the failover controller.
+type Router interface {
+	RouteStatus(name string) (microtik.RouteResult, error)
+	UpdateRoute(name string, ops ...microtik.RouteOp) error
+	Reset4G() error
+	Ping(target string, opts microtik.PingOptions) (connectivity.PingResult, error)
+}
+
+// State describes which uplink is currently in use.
+type State string
+
+const (
+	// StatePrimary means that the traffic is routed through the primary uplink.
+	StatePrimary State = "primary"
+	// StateBackup means that the primary route has been disabled and the
+	// traffic is routed through the backup uplink.
+	StateBackup State = "backup"
+	// StateTrial means that the primary route has been re-enabled after
+	// a failover and has to prove that it is stable again.
+	StateTrial State = "trial"
+)
+
+// Status is a snapshot of the failover controller's state.
+type Status struct {
+	Primary    string                   `json:"primary"`
+	Backup     string                   `json:"backup"`
+	State      State                    `json:"state"`
+	Active     string                   `json:"active"`
+	Healthy    bool                     `json:"healthy"`
+	Failures   int                      `json:"failures"`
+	Successes  int                      `json:"successes"`
+	HoldDown   time.Duration            `json:"hold_down"`
+	LastProbe  time.Time                `json:"last_probe"`
+	LastSwitch time.Time                `json:"last_switch"`
+	LastReset  time.Time                `json:"last_reset"`
+	LastError  string                   `json:"last_error,omitempty"`
+	Probe      connectivity.PingResults `json:"probe"`
+	// Standby, StandbyHealthy and StandbyProbe describe the uplink which
+	// is not in use. They are only available if the uplinks are probed
+	// through the router (see UplinkInterfaces).
+	Standby        string                   `json:"standby,omitempty"`
+	StandbyHealthy bool                     `json:"standby_healthy"`
+	StandbyProbe   connectivity.PingResults `json:"standby_probe,omitempty"`
+}
+
+// Controller continuously probes the uplinks and switches between a
+// primary and a backup route on a microtik router. The primary route is
+// expected to have a lower distance than the backup route, so that
+// disabling the primary route lets the router fall back on the backup
+// route.
+//
+// By default the probes are sent from the host running infractl, so only
+// the uplink in use can be tested. If the interfaces of the uplinks are
+// known (see UplinkInterfaces), the router pings through each uplink
+// instead. In this case the controller doesn't fail over to a backup
+// uplink which is down as well and only tries the primary uplink again
+// once it replies.
+//
+// After a failover the controller waits for the hold-down time and then
+// re-enables the primary route on trial. If the primary uplink fails
+// during the trial, the controller falls back again immediately and
+// doubles the hold-down time (hysteresis).
+type Controller struct {
+	sync.Mutex
+	router           Router
+	primary          string
+	backup           string
+	hosts            []string
+	interfaces       map[string]string
+	interval         time.Duration
+	timeout          time.Duration
+	samples          int
+	maxLoss          float64
+	maxRTT           time.Duration
+	failThreshold    int
+	recoverThreshold int
+	holdDown         time.Duration
+	maxHoldDown      time.Duration
+	resetThreshold   int
+	resetHoldDown    time.Duration
+	status           Status
+	closeOnce        sync.Once
+	closeCh          chan struct{}
+}
+
+// Option is the type used for functional options
+type Option func(*Controller)
+
+// New returns a failover controller which switches between the primary and
+// the backup route. The routes must be registered in the router.
+func New(r Router, primary, backup string, opts ...Option) *Controller {
+
+	c := &Controller{
+		router:           r,
+		primary:          primary,
+		backup:           backup,
+		hosts:            []string{"8.8.8.8", "1.1.1.1"},
+		interval:         time.Second * 10,
+		timeout:          time.Second * 3,
+		samples:          3,
+		maxLoss:          50,
+		maxRTT:           0,
+		failThreshold:    3,
+		recoverThreshold: 3,
+		holdDown:         time.Minute * 5,
+		maxHoldDown:      time.Hour,
+		resetThreshold:   3,
+		resetHoldDown:    time.Minute * 10,
+		closeCh:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.status = Status{
+		Primary:  primary,
+		Backup:   backup,
+		State:    StatePrimary,
+		Active:   primary,
+		HoldDown: c.holdDown,
+		Probe:    make(connectivity.PingResults),
+	}
+
+	if c.probeStandby() {
+		c.status.Standby = backup
+	}
+
+	return c
+}
+
+// Status returns a snapshot of the controller's current state.
+func (c *Controller) Status() Status {
+	c.Lock()
+	defer c.Unlock()
+	return c.status
+}
+
+// Close stops the controller.
+func (c *Controller) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
+}
+
+// Run probes the uplinks in the configured interval and switches the routes
+// if necessary. It blocks until Close is called and should therefore be
+// executed in a go routine.
+func (c *Controller) Run() {
+
+	log.Printf("failover: monitoring uplinks %s (primary) and %s (backup) every %v\n",
+		c.primary, c.backup, c.interval)
+
+	if err := c.syncState(); err != nil {
+		log.Println("failover:", err)
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.step()
+		select {
+		case <-c.closeCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncState derives the initial state from the router, so that a failover
+// performed before a restart of infractl is not undone.
+func (c *Controller) syncState() error {
+	res, err := c.router.RouteStatus(c.primary)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if res["disabled"] {
+		c.status.State = StateBackup
+		c.status.Active = c.backup
+		c.status.LastSwitch = time.Now()
+		if c.probeStandby() {
+			c.status.Standby = c.primary
+		}
+	}
+
+	return nil
+}
+
+// probeStandby returns true if the uplinks are probed through the router,
+// which allows to test the uplink on standby as well.
+func (c *Controller) probeStandby() bool {
+	return len(c.interfaces[c.primary]) > 0 && len(c.interfaces[c.backup]) > 0
+}
+
+// action describes the router calls decided upon in a probe cycle.
+type action struct {
+	switchTo State // empty if the routes remain unchanged
+	reset    bool  // power cycle the 4G modem after the switch
+}
+
+// step executes one probe cycle. The lock is not held while probing and
+// while the routes are modified, so that Status doesn't block. Since step
+// is only executed by Run, the state can't change in the meantime.
+func (c *Controller) step() {
+
+	c.Lock()
+	active := c.status.Active
+	standby := c.status.Standby
+	c.Unlock()
+
+	var results, standbyResults connectivity.PingResults
+
+	if c.probeStandby() {
+		results = c.probe(active)
+		standbyResults = c.probe(standby)
+	} else {
+		results = connectivity.PingHosts(c.hosts, c.timeout, c.samples)
+	}
+	healthy := c.evaluate(results)
+
+	c.Lock()
+
+	c.status.LastProbe = time.Now()
+	c.status.Probe = results
+	c.status.Healthy = healthy
+	c.status.LastError = ""
+
+	if standbyResults != nil {
+		c.status.StandbyProbe = standbyResults
+		c.status.StandbyHealthy = c.evaluate(standbyResults)
+	}
+
+	if healthy {
+		c.status.Failures = 0
+		c.status.Successes++
+	} else {
+		c.status.Successes = 0
+		c.status.Failures++
+	}
+
+	var act action
+	var err error
+
+	switch c.status.State {
+	case StatePrimary:
+		act, err = c.handlePrimary(healthy)
+	case StateTrial:
+		act, err = c.handleTrial(healthy)
+	case StateBackup:
+		act, err = c.handleBackup(healthy)
+	}
+
+	c.Unlock()
+
+	if err == nil {
+		err = c.execute(act)
+	}
+
+	if err != nil {
+		c.Lock()
+		c.status.LastError = err.Error()
+		c.Unlock()
+		log.Println("failover:", err)
+	}
+}
+
+// handlePrimary fails over to the backup uplink after the configured
+// amount of consecutive failed probes, unless the backup uplink is known
+// to be down as well. The caller must hold the lock.
+func (c *Controller) handlePrimary(healthy bool) (action, error) {
+	if healthy || c.status.Failures < c.failThreshold {
+		return action{}, nil
+	}
+	if c.probeStandby() && !c.status.StandbyHealthy {
+		return action{}, fmt.Errorf("not failing over, %s is down as well", c.backup)
+	}
+	return action{switchTo: StateBackup}, nil
+}
+
+// handleTrial decides if the primary uplink has recovered. A single failed
+// probe during the trial is sufficient to fall back to the backup uplink.
+// The caller must hold the lock.
+func (c *Controller) handleTrial(healthy bool) (action, error) {
+	if !healthy {
+		c.status.HoldDown = c.status.HoldDown * 2
+		if c.status.HoldDown > c.maxHoldDown {
+			c.status.HoldDown = c.maxHoldDown
+		}
+		log.Printf("failover: %s did not recover, next trial in %v\n",
+			c.primary, c.status.HoldDown)
+		return action{switchTo: StateBackup}, nil
+	}
+	if c.status.Successes >= c.recoverThreshold {
+		log.Printf("failover: %s recovered\n", c.primary)
+		c.status.State = StatePrimary
+		c.status.HoldDown = c.holdDown
+	}
+	return action{}, nil
+}
+
+// handleBackup re-enables the primary uplink on trial once the hold-down
+// time has expired and, if it can be probed, the primary uplink replies.
+// If the backup uplink is down as well, the primary route is enabled
+// immediately and the 4G modem is power cycled (at most once within the
+// reset hold-down time). The caller must hold the lock.
+func (c *Controller) handleBackup(healthy bool) (action, error) {
+
+	if !healthy && c.status.Failures >= c.resetThreshold {
+		return action{
+			switchTo: StateTrial,
+			reset:    time.Since(c.status.LastReset) >= c.resetHoldDown,
+		}, nil
+	}
+
+	if time.Since(c.status.LastSwitch) < c.status.HoldDown {
+		return action{}, nil
+	}
+
+	if c.probeStandby() && !c.status.StandbyHealthy {
+		return action{}, nil
+	}
+
+	return action{switchTo: StateTrial}, nil
+}
+
+// execute performs the router calls of an action. The caller must not
+// hold the lock.
+func (c *Controller) execute(act action) error {
+	if len(act.switchTo) == 0 {
+		return nil
+	}
+	if err := c.switchTo(act.switchTo); err != nil {
+		return err
+	}
+	if act.reset {
+		return c.reset()
+	}
+	return nil
+}
+
+// switchTo enables or disables the primary route and updates the state
+// once the router has applied the change. The caller must not hold the
+// lock.
+func (c *Controller) switchTo(state State) error {
+
+	disabled := state == StateBackup
+	op := microtik.Enable()
+	if disabled {
+		op = microtik.Disable()
+	}
+	if err := c.router.UpdateRoute(c.primary, op); err != nil {
+		return fmt.Errorf("unable to switch to %s uplink: %v", state, err)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	log.Printf("failover: switched from %s to %s\n", c.status.State, state)
+
+	c.status.State = state
+	c.status.Active = c.primary
+	if disabled {
+		c.status.Active = c.backup
+	}
+	if c.probeStandby() {
+		c.status.Standby = c.backup
+		if disabled {
+			c.status.Standby = c.primary
+		}
+		c.status.StandbyHealthy = false
+		c.status.StandbyProbe = nil
+	}
+	c.status.LastSwitch = time.Now()
+	c.status.Failures = 0
+	c.status.Successes = 0
+
+	return nil
+}
+
+// reset power cycles the 4G modem. The caller must not hold the lock.
+func (c *Controller) reset() error {
+	log.Printf("failover: %s seems to be dead, resetting 4G modem\n", c.backup)
+	c.Lock()
+	c.status.LastReset = time.Now()
+	c.Unlock()
+	if err := c.router.Reset4G(); err != nil {
+		return fmt.Errorf("unable to reset 4G modem: %v", err)
+	}
+	return nil
+}
+
+// probe pings the hosts from the router through the interface of the
+// provided uplink.
+func (c *Controller) probe(uplink string) connectivity.PingResults {
+
+	opts := microtik.PingOptions{
+		Count:     c.samples,
+		Interface: c.interfaces[uplink],
+	}
+	// send all pings within the timeout
+	if c.samples > 0 {
+		opts.Interval = c.timeout / time.Duration(c.samples)
+	}
+
+	results := make(connectivity.PingResults)
+	for _, host := range c.hosts {
+		res, err := c.router.Ping(host, opts)
+		if err != nil {
+			log.Printf("failover: unable to ping %s through %s: %v\n", host, uplink, err)
+		}
+		results[host] = res
+	}
+
+	return results
+}
+
+// evaluate applies the configured thresholds on the results of a probe
+// cycle. The uplink is considered healthy if the average packet loss over
+// all hosts and the average round trip time of the reachable hosts are
+// within the limits.
+func (c *Controller) evaluate(results connectivity.PingResults) bool {
+
+	if len(results) == 0 {
+		return false
+	}
+
+	var loss float64
+	var rtt time.Duration
+	reachable := 0
+
+	for _, r := range results {
+		if r.Failed {
+			loss += 100
+			continue
+		}
+		loss += r.Loss
+		if r.Loss < 100 {
+			rtt += r.RTT
+			reachable++
+		}
+	}
+
+	if reachable == 0 {
+		return false
+	}
+
+	if loss/float64(len(results)) > c.maxLoss {
+		return false
+	}
+
+	if c.maxRTT > 0 && rtt/time.Duration(reachable) > c.maxRTT {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,185 @@
+package failover
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/infractl/connectivity"
+	"github.com/dh1tw/infractl/microtik"
+)
+
+// fakeRouter replies to pings through the interfaces which are up and
+// records the modifications of the routes and the resets of the modem.
+type fakeRouter struct {
+	t          *testing.T
+	up         map[string]bool
+	failUpdate bool
+	calls      []string
+	status     func() Status
+}
+
+func (r *fakeRouter) RouteStatus(name string) (microtik.RouteResult, error) {
+	return microtik.RouteResult{}, nil
+}
+
+func (r *fakeRouter) UpdateRoute(name string, ops ...microtik.RouteOp) error {
+	r.checkUnlocked()
+	switch {
+	case reflect.DeepEqual(ops, []microtik.RouteOp{microtik.Enable()}):
+		r.calls = append(r.calls, "enable "+name)
+	case reflect.DeepEqual(ops, []microtik.RouteOp{microtik.Disable()}):
+		r.calls = append(r.calls, "disable "+name)
+	default:
+		r.t.Errorf("unexpected route ops %v", ops)
+	}
+	if r.failUpdate {
+		return errors.New("router unreachable")
+	}
+	return nil
+}
+
+func (r *fakeRouter) Reset4G() error {
+	r.checkUnlocked()
+	r.calls = append(r.calls, "reset")
+	return nil
+}
+
+func (r *fakeRouter) Ping(target string, opts microtik.PingOptions) (connectivity.PingResult, error) {
+	if !r.up[opts.Interface] {
+		return connectivity.PingResult{Address: target, Loss: 100, Failed: true}, nil
+	}
+	return connectivity.PingResult{Address: target, RTT: time.Millisecond * 10}, nil
+}
+
+// checkUnlocked verifies that the status of the controller can be read
+// while the router is being modified.
+func (r *fakeRouter) checkUnlocked() {
+	done := make(chan struct{})
+	go func() {
+		r.status()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		r.t.Error("Status blocked during a router call")
+	}
+}
+
+func TestControllerStep(t *testing.T) {
+
+	// uplinks which are up during a probe cycle
+	const (
+		both    = "both"
+		primary = "adsl"
+		backup  = "4g"
+		none    = "none"
+	)
+
+	tt := []struct {
+		name       string
+		steps      []string
+		failUpdate bool
+		states     []State
+		calls      []string
+		holdDown   time.Duration
+		lastError  bool
+	}{
+		{
+			name:   "primary healthy",
+			steps:  []string{both, both},
+			states: []State{StatePrimary, StatePrimary},
+		},
+		{
+			name:   "failover after threshold",
+			steps:  []string{backup, backup},
+			states: []State{StatePrimary, StateBackup},
+			calls:  []string{"disable adsl"},
+		},
+		{
+			name:      "no failover if backup is down as well",
+			steps:     []string{none, none},
+			states:    []State{StatePrimary, StatePrimary},
+			lastError: true,
+		},
+		{
+			name:   "no trial while primary is down",
+			steps:  []string{backup, backup, backup},
+			states: []State{StatePrimary, StateBackup, StateBackup},
+			calls:  []string{"disable adsl"},
+		},
+		{
+			name:   "trial and recovery",
+			steps:  []string{backup, backup, both, both, both},
+			states: []State{StatePrimary, StateBackup, StateTrial, StateTrial, StatePrimary},
+			calls:  []string{"disable adsl", "enable adsl"},
+		},
+		{
+			name:     "failed trial doubles hold-down",
+			steps:    []string{backup, backup, both, backup},
+			states:   []State{StatePrimary, StateBackup, StateTrial, StateBackup},
+			calls:    []string{"disable adsl", "enable adsl", "disable adsl"},
+			holdDown: time.Nanosecond * 2,
+		},
+		{
+			name:   "reset 4g if backup is down",
+			steps:  []string{backup, backup, none, none},
+			states: []State{StatePrimary, StateBackup, StateBackup, StateTrial},
+			calls:  []string{"disable adsl", "enable adsl", "reset"},
+		},
+		{
+			name:       "failed switch keeps state",
+			steps:      []string{backup, backup},
+			failUpdate: true,
+			states:     []State{StatePrimary, StatePrimary},
+			calls:      []string{"disable adsl"},
+			lastError:  true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+
+			r := &fakeRouter{t: t, failUpdate: tc.failUpdate}
+
+			c := New(r, "adsl", "4g",
+				UplinkInterfaces(map[string]string{"adsl": "pppoe-out1", "4g": "lte1"}),
+				PingTimeout(time.Millisecond),
+				FailThreshold(2),
+				RecoverThreshold(2),
+				HoldDown(time.Nanosecond, time.Nanosecond*4),
+				ResetThreshold(2, time.Hour),
+			)
+			r.status = c.Status
+
+			for i, step := range tc.steps {
+				r.up = map[string]bool{
+					"pppoe-out1": step == both || step == primary,
+					"lte1":       step == both || step == backup,
+				}
+				c.step()
+				if state := c.Status().State; state != tc.states[i] {
+					t.Errorf("step %d: got state %s, expected %s", i, state, tc.states[i])
+				}
+			}
+
+			if !reflect.DeepEqual(r.calls, tc.calls) {
+				t.Errorf("got router calls %v, expected %v", r.calls, tc.calls)
+			}
+
+			status := c.Status()
+			holdDown := tc.holdDown
+			if holdDown == 0 {
+				holdDown = time.Nanosecond
+			}
+			if status.HoldDown != holdDown {
+				t.Errorf("got hold-down %v, expected %v", status.HoldDown, holdDown)
+			}
+			if (len(status.LastError) > 0) != tc.lastError {
+				t.Errorf("unexpected last error %q", status.LastError)
+			}
+		})
+	}
+}
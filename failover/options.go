@@ -0,0 +1,95 @@
+package failover
+
+import "time"
+
+// Hosts is a functional option which sets the hosts which will be pinged
+// in order to determine the health of the uplinks.
+func Hosts(hosts []string) Option {
+	return func(c *Controller) {
+		c.hosts = hosts
+	}
+}
+
+// UplinkInterfaces sets the interfaces of the uplinks on the router, indexed
+// by the names of the routes. If the interfaces of the primary and the
+// backup uplink are known, the hosts are pinged from the router through
+// each uplink, so that the uplink on standby is probed as well.
+func UplinkInterfaces(interfaces map[string]string) Option {
+	return func(c *Controller) {
+		c.interfaces = interfaces
+	}
+}
+
+// Interval is a functional option which sets the interval between two
+// probe cycles.
+func Interval(interval time.Duration) Option {
+	return func(c *Controller) {
+		c.interval = interval
+	}
+}
+
+// PingTimeout sets the timeout for the pings of each probe cycle.
+func PingTimeout(timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.timeout = timeout
+	}
+}
+
+// PingSamples sets the amount of pings sent to each host per probe cycle.
+func PingSamples(samples int) Option {
+	return func(c *Controller) {
+		c.samples = samples
+	}
+}
+
+// MaxLoss sets the maximum average packet loss (in percent) which is
+// tolerated before a probe cycle is considered failed.
+func MaxLoss(loss float64) Option {
+	return func(c *Controller) {
+		c.maxLoss = loss
+	}
+}
+
+// MaxRTT sets the maximum average round trip time which is tolerated before
+// a probe cycle is considered failed. A value of 0 disables this check.
+func MaxRTT(rtt time.Duration) Option {
+	return func(c *Controller) {
+		c.maxRTT = rtt
+	}
+}
+
+// FailThreshold sets the amount of consecutive failed probe cycles after
+// which the controller fails over to the backup uplink.
+func FailThreshold(n int) Option {
+	return func(c *Controller) {
+		c.failThreshold = n
+	}
+}
+
+// RecoverThreshold sets the amount of consecutive successful probe cycles
+// during a trial after which the primary uplink is considered recovered.
+func RecoverThreshold(n int) Option {
+	return func(c *Controller) {
+		c.recoverThreshold = n
+	}
+}
+
+// HoldDown sets the minimum time the controller stays on the backup uplink
+// before the primary uplink is tried again. Each failed trial doubles this
+// time, up to the provided maximum.
+func HoldDown(holdDown, max time.Duration) Option {
+	return func(c *Controller) {
+		c.holdDown = holdDown
+		c.maxHoldDown = max
+	}
+}
+
+// ResetThreshold sets the amount of consecutive failed probe cycles on the
+// backup uplink after which the 4G modem will be power cycled. Between two
+// resets at least the provided hold-down time has to pass.
+func ResetThreshold(n int, holdDown time.Duration) Option {
+	return func(c *Controller) {
+		c.resetThreshold = n
+		c.resetHoldDown = holdDown
+	}
+}
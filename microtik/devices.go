@@ -0,0 +1,113 @@
+package microtik
+
+import (
+	"strings"
+	"time"
+)
+
+// DHCPLease is a lease of the DHCP server (/ip/dhcp-server/lease) of the
+// router.
+type DHCPLease struct {
+	ID         string `json:"id"`
+	Address    string `json:"address"`
+	MACAddress string `json:"mac_address"`
+	HostName   string `json:"host_name"`
+	Server     string `json:"server"`
+	// Status is e.g. bound, waiting or offered
+	Status   string `json:"status"`
+	Dynamic  bool   `json:"dynamic"`
+	Disabled bool   `json:"disabled"`
+	Comment  string `json:"comment"`
+	// LastSeen is the time since the client was last seen (0 if unknown)
+	LastSeen     time.Duration `json:"last_seen"`
+	ExpiresAfter time.Duration `json:"expires_after"`
+}
+
+// Bound returns true if the lease is currently assigned to a client.
+func (l DHCPLease) Bound() bool {
+	return l.Status == "bound"
+}
+
+// DHCPLeases returns the leases of the DHCP servers of the router.
+func (m *Microtik) DHCPLeases() ([]DHCPLease, error) {
+
+	reply, err := m.run("/ip/dhcp-server/lease/print")
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]DHCPLease, 0, len(reply))
+	for _, r := range reply {
+		l := DHCPLease{
+			ID:         r[".id"],
+			Address:    r["address"],
+			MACAddress: strings.ToUpper(r["mac-address"]),
+			HostName:   r["host-name"],
+			Server:     r["server"],
+			Status:     r["status"],
+			Dynamic:    r["dynamic"] == "true",
+			Disabled:   r["disabled"] == "true",
+			Comment:    r["comment"],
+		}
+		// RouterOS v7 reports the address which has actually been assigned
+		// separately from the (static) address of the lease
+		if active := r["active-address"]; len(active) > 0 {
+			l.Address = active
+		}
+		if d, err := ParseDuration(r["last-seen"]); err == nil {
+			l.LastSeen = d
+		}
+		if d, err := ParseDuration(r["expires-after"]); err == nil {
+			l.ExpiresAfter = d
+		}
+		leases = append(leases, l)
+	}
+
+	return leases, nil
+}
+
+// ARPEntry is an entry of the ARP table (/ip/arp) of the router.
+type ARPEntry struct {
+	ID         string `json:"id"`
+	Address    string `json:"address"`
+	MACAddress string `json:"mac_address"`
+	Interface  string `json:"interface"`
+	// Complete is true if the neighbour has answered the ARP request
+	Complete bool   `json:"complete"`
+	Dynamic  bool   `json:"dynamic"`
+	Disabled bool   `json:"disabled"`
+	Comment  string `json:"comment"`
+}
+
+// ARP returns the entries of the ARP table of the router.
+func (m *Microtik) ARP() ([]ARPEntry, error) {
+
+	reply, err := m.run("/ip/arp/print")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ARPEntry, 0, len(reply))
+	for _, r := range reply {
+		e := ARPEntry{
+			ID:         r[".id"],
+			Address:    r["address"],
+			MACAddress: strings.ToUpper(r["mac-address"]),
+			Interface:  r["interface"],
+			Dynamic:    r["dynamic"] == "true",
+			Disabled:   r["disabled"] == "true",
+			Comment:    r["comment"],
+		}
+		// RouterOS v7 replaced the complete flag with the state of the
+		// neighbour (e.g. reachable, stale, failed)
+		switch r["status"] {
+		case "":
+			e.Complete = r["complete"] == "true"
+		case "reachable", "stale", "delay", "probe", "permanent":
+			e.Complete = true
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,83 @@
+package microtik
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/routeros.v2"
+)
+
+// Errors returned by the methods of Microtik. They are usually wrapped
+// with additional details and have to be checked with errors.Is.
+var (
+	// ErrUnknownRoute is returned if a route has not been registered
+	// (see RouteID and RouteMatch).
+	ErrUnknownRoute = errors.New("unknown route")
+	// ErrRouteNotFound is returned if no route, or more than one route, on
+	// the router matches a registered route.
+	ErrRouteNotFound = errors.New("unable to find route")
+	// ErrInvalidRouteOp is returned if the modifications of a route are
+	// invalid or not allowed (see RouteAttributes).
+	ErrInvalidRouteOp = errors.New("invalid route modification")
+	// ErrAuth is returned if the router rejects the credentials.
+	ErrAuth = errors.New("authentication failed")
+	// ErrUnreachable is returned if the router can't be reached, doesn't
+	// reply within the timeout or the connection breaks.
+	ErrUnreachable = errors.New("router unreachable")
+	// ErrRouterTrap is returned if the router rejects a command. The
+	// message of the router can be retrieved through TrapError.
+	ErrRouterTrap = errors.New("command rejected by the router")
+	// ErrListNotAllowed is returned if an address list may not be modified
+	// (see AddressList).
+	ErrListNotAllowed = errors.New("modifying address list not allowed")
+	// ErrInvalidAddress is returned if an ip address or network can't be
+	// parsed.
+	ErrInvalidAddress = errors.New("invalid address")
+	// ErrAddressNotFound is returned if an address is not contained in an
+	// address list.
+	ErrAddressNotFound = errors.New("address not found")
+	// ErrUnknownNatRule is returned if a NAT rule has not been registered
+	// (see NatRuleID).
+	ErrUnknownNatRule = errors.New("unknown nat rule")
+	// ErrNatRuleNotFound is returned if no rule, or more than one rule, on
+	// the router matches a registered NAT rule.
+	ErrNatRuleNotFound = errors.New("unable to find nat rule")
+	// ErrTransactionRunning is returned if routes are modified while a
+	// transaction is running (see Transaction).
+	ErrTransactionRunning = errors.New("route transaction in progress")
+)
+
+// TrapError is returned if the router rejects a command (!trap on the
+// API, HTTP error on the REST API). It matches ErrRouterTrap.
+type TrapError struct {
+	// Command is the rejected command, e.g. /ip/route/set
+	Command string
+	// Message is the error message of the router
+	Message string
+}
+
+func (e *TrapError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Command, e.Message)
+}
+
+// Is returns true if target is ErrRouterTrap.
+func (e *TrapError) Is(target error) bool {
+	return target == ErrRouterTrap
+}
+
+// apiError converts an error of the routeros client into a TrapError if
+// the router rejected the command. Any other error means that the
+// connection is broken.
+func apiError(command string, err error) error {
+
+	var devErr *routeros.DeviceError
+	if errors.As(err, &devErr) {
+		msg := devErr.Sentence.Map["message"]
+		if len(msg) == 0 {
+			msg = devErr.Error()
+		}
+		return &TrapError{Command: command, Message: msg}
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnreachable, err)
+}
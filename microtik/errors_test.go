@@ -0,0 +1,88 @@
+package microtik
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik/routerostest"
+)
+
+func TestErrors(t *testing.T) {
+
+	srv := newTestRoutes()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, testRoutes...)
+
+	if _, err := m.RouteStatus("vdsl"); !errors.Is(err, ErrUnknownRoute) {
+		t.Errorf("unknown route: got %v", err)
+	}
+
+	if _, err := m.RouteStatus("missing"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("missing route: got %v", err)
+	}
+
+	if err := m.UpdateRoute("missing", Enable()); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("missing route: got %v", err)
+	}
+
+	srv.Fail("/ip/route/set", "failure: route is read-only")
+
+	err := m.UpdateRoute("adsl", Disable())
+	var trapErr *TrapError
+	if !errors.Is(err, ErrRouterTrap) || !errors.As(err, &trapErr) {
+		t.Fatalf("trap: got %v", err)
+	}
+	if trapErr.Command != "/ip/route/set" || trapErr.Message != "failure: route is read-only" {
+		t.Errorf("trap: got %+v", trapErr)
+	}
+	if errors.Is(err, ErrUnreachable) {
+		t.Errorf("trap matches ErrUnreachable")
+	}
+}
+
+func TestErrAuth(t *testing.T) {
+
+	srv := routerostest.NewServer(routerostest.Credentials("admin", "secret"))
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, Backoff(time.Minute, time.Minute))
+
+	if _, err := m.run("/system/identity/print"); !errors.Is(err, ErrAuth) {
+		t.Fatalf("got %v", err)
+	}
+
+	// the cause is kept while waiting for the next connection attempt
+	if _, err := m.run("/system/identity/print"); !errors.Is(err, ErrAuth) {
+		t.Errorf("backoff: got %v", err)
+	}
+}
+
+func TestErrUnreachable(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, Timeout(time.Millisecond*100), Backoff(time.Minute, time.Minute))
+
+	srv.Delay("/system/identity/print", time.Millisecond*300)
+	if _, err := m.run("/system/identity/print"); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("timeout: got %v", err)
+	}
+	srv.ClearFaults()
+
+	srv.Hangup("/system/identity/print")
+	if _, err := m.run("/system/identity/print"); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("hangup: got %v", err)
+	}
+	srv.ClearFaults()
+
+	srv.Close()
+	if _, err := m.run("/system/identity/print"); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("connection refused: got %v", err)
+	}
+	if _, err := m.run("/system/identity/print"); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("backoff: got %v", err)
+	}
+}
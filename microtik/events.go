@@ -0,0 +1,386 @@
+package microtik
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Types of the events reported by Listen
+const (
+	RouteActivated    = "route_activated"
+	RouteDeactivated  = "route_deactivated"
+	RouteEnabled      = "route_enabled"
+	RouteDisabled     = "route_disabled"
+	InterfaceUp       = "interface_up"
+	InterfaceDown     = "interface_down"
+	InterfaceEnabled  = "interface_enabled"
+	InterfaceDisabled = "interface_disabled"
+	// ListenConnected and ListenDisconnected report the state of the
+	// connection through which the changes are received
+	ListenConnected    = "connected"
+	ListenDisconnected = "disconnected"
+)
+
+// Event describes a change of a registered route or an interface of the
+// router.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Route     string    `json:"route,omitempty"`
+	Interface string    `json:"interface,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// pollInterval is used to detect changes through the REST API, which
+// doesn't support listening.
+const pollInterval = time.Second * 5
+
+// Listen subscribes to the changes of the routes (/ip/route/listen) and
+// the interfaces (/interface/listen) of the router and calls fn for every
+// transition of a registered route (active, disabled) and of an interface
+// (running, disabled). Listen returns immediately; fn is called from a
+// background routine until Close is called.
+//
+// The changes are received through a dedicated connection. If it breaks,
+// it will be re-established with an exponential backoff and the
+// transitions which happened in the meantime are reported afterwards.
+// The REST API doesn't support listening, therefore the router is polled
+// instead.
+func (m *Microtik) Listen(fn func(Event)) {
+	go m.listen(&watcher{
+		m:      m,
+		fn:     fn,
+		routes: make(map[string]map[string]string),
+		ifaces: make(map[string]map[string]string),
+	})
+}
+
+func (m *Microtik) listen(w *watcher) {
+
+	backoff := m.minBackoff
+
+	for {
+		start := time.Now()
+
+		var err error
+		if m.config.Transport == TransportREST {
+			err = m.poll(w)
+		} else {
+			err = m.listenAPI(w)
+		}
+
+		select {
+		case <-m.closeCh:
+			return
+		default:
+		}
+
+		if w.connected {
+			w.connected = false
+			w.emit(Event{Type: ListenDisconnected, Message: fmt.Sprintf("connection lost: %v", err)})
+		}
+
+		// start over after a stable connection
+		if time.Since(start) > m.maxBackoff {
+			backoff = m.minBackoff
+		}
+
+		select {
+		case <-m.closeCh:
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = backoff * 2
+		if backoff > m.maxBackoff {
+			backoff = m.maxBackoff
+		}
+	}
+}
+
+// listenAPI receives the changes through a dedicated API connection
+// until it breaks or Close is called.
+func (m *Microtik) listenAPI(w *watcher) error {
+
+	t, err := dialAPI(m.config, m.timeout)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// closing the connection terminates the listeners
+	go func() {
+		select {
+		case <-m.closeCh:
+		case <-done:
+		}
+		t.close()
+	}()
+
+	if err := w.snapshot(t.run(m.timeout, "/ip/route/print")); err != nil {
+		return err
+	}
+	if err := w.snapshotInterfaces(t.run(m.timeout, "/interface/print")); err != nil {
+		return err
+	}
+
+	t.client.Queue = 100
+	errC := t.errC
+
+	routes, err := t.client.Listen("/ip/route/listen")
+	if err != nil {
+		return err
+	}
+	ifaces, err := t.client.Listen("/interface/listen")
+	if err != nil {
+		return err
+	}
+
+	w.connected = true
+	w.emit(Event{Type: ListenConnected, Message: "listening for changes on router " + m.config.Address})
+
+	// the listeners are silent while nothing changes, therefore a broken
+	// connection is detected by periodically sending a command
+	go func() {
+		interval := m.keepAlive
+		if interval <= 0 {
+			interval = time.Second * 30
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := t.run(m.timeout, "/system/identity/print"); err != nil {
+					t.close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case sen, ok := <-routes.Chan():
+			if !ok {
+				return listenError(routes.Err())
+			}
+			w.updateRoute(sen.Map)
+		case sen, ok := <-ifaces.Chan():
+			if !ok {
+				return listenError(ifaces.Err())
+			}
+			w.updateInterface(sen.Map)
+		case err, ok := <-errC:
+			if ok && err != nil {
+				return err
+			}
+			return listenError(nil)
+		case <-m.closeCh:
+			return nil
+		}
+	}
+}
+
+func listenError(err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("listener terminated")
+}
+
+// poll detects the changes by periodically retrieving the routes and the
+// interfaces through the shared connection.
+func (m *Microtik) poll(w *watcher) error {
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.snapshot(m.run("/ip/route/print")); err != nil {
+			return err
+		}
+		if err := w.snapshotInterfaces(m.run("/interface/print")); err != nil {
+			return err
+		}
+
+		if !w.connected {
+			w.connected = true
+			w.emit(Event{Type: ListenConnected, Message: "polling for changes on router " + m.config.Address})
+		}
+
+		select {
+		case <-m.closeCh:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watcher keeps the last known attributes of the routes and interfaces
+// (indexed by their .id) in order to detect transitions.
+type watcher struct {
+	m         *Microtik
+	fn        func(Event)
+	routes    map[string]map[string]string
+	ifaces    map[string]map[string]string
+	connected bool
+}
+
+func (w *watcher) emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	w.fn(e)
+}
+
+// snapshot replaces the known routes with the reply of /ip/route/print
+// and reports the transitions since the last snapshot.
+func (w *watcher) snapshot(reply []map[string]string, err error) error {
+	if err != nil {
+		return err
+	}
+	routes := make(map[string]map[string]string, len(reply))
+	for _, r := range reply {
+		id := r[".id"]
+		routes[id] = r
+		if prev, ok := w.routes[id]; ok {
+			w.routeChanged(prev, r)
+		}
+	}
+	w.routes = routes
+	return nil
+}
+
+// snapshotInterfaces replaces the known interfaces with the reply of
+// /interface/print and reports the transitions since the last snapshot.
+func (w *watcher) snapshotInterfaces(reply []map[string]string, err error) error {
+	if err != nil {
+		return err
+	}
+	ifaces := make(map[string]map[string]string, len(reply))
+	for _, r := range reply {
+		id := r[".id"]
+		ifaces[id] = r
+		if prev, ok := w.ifaces[id]; ok {
+			w.interfaceChanged(prev, r)
+		}
+	}
+	w.ifaces = ifaces
+	return nil
+}
+
+// updateRoute applies an item received from /ip/route/listen.
+func (w *watcher) updateRoute(item map[string]string) {
+	id := item[".id"]
+	if item[".dead"] == "true" {
+		delete(w.routes, id)
+		return
+	}
+	prev, ok := w.routes[id]
+	cur := merge(prev, item)
+	w.routes[id] = cur
+	if ok {
+		w.routeChanged(prev, cur)
+	}
+}
+
+// updateInterface applies an item received from /interface/listen.
+func (w *watcher) updateInterface(item map[string]string) {
+	id := item[".id"]
+	if item[".dead"] == "true" {
+		delete(w.ifaces, id)
+		return
+	}
+	prev, ok := w.ifaces[id]
+	cur := merge(prev, item)
+	w.ifaces[id] = cur
+	if ok {
+		w.interfaceChanged(prev, cur)
+	}
+}
+
+// merge returns the attributes of an item updated with the changed
+// attributes.
+func merge(prev, update map[string]string) map[string]string {
+	cur := make(map[string]string, len(prev)+len(update))
+	for k, v := range prev {
+		cur[k] = v
+	}
+	for k, v := range update {
+		cur[k] = v
+	}
+	return cur
+}
+
+// routeChanged reports the transitions of a registered route.
+func (w *watcher) routeChanged(prev, cur map[string]string) {
+
+	r := parseRoute(cur)
+	name, ok := w.m.routeName(r)
+	if !ok {
+		return
+	}
+	p := parseRoute(prev)
+
+	if p.Disabled != r.Disabled {
+		e := Event{Type: RouteEnabled, Route: name, Message: "route " + name + " enabled"}
+		if r.Disabled {
+			e = Event{Type: RouteDisabled, Route: name, Message: "route " + name + " disabled"}
+		}
+		w.emit(e)
+	}
+
+	if p.Active != r.Active {
+		e := Event{Type: RouteActivated, Route: name, Message: "route " + name + " active"}
+		if !r.Active {
+			e = Event{Type: RouteDeactivated, Route: name, Message: "route " + name + " inactive"}
+		}
+		w.emit(e)
+	}
+}
+
+// interfaceChanged reports the transitions of an interface.
+func (w *watcher) interfaceChanged(prev, cur map[string]string) {
+
+	name := cur["name"]
+
+	if prev["disabled"] != cur["disabled"] {
+		e := Event{Type: InterfaceEnabled, Interface: name, Message: "interface " + name + " enabled"}
+		if cur["disabled"] == "true" {
+			e = Event{Type: InterfaceDisabled, Interface: name, Message: "interface " + name + " disabled"}
+		}
+		w.emit(e)
+	}
+
+	if prev["running"] != cur["running"] {
+		e := Event{Type: InterfaceUp, Interface: name, Message: "interface " + name + " running"}
+		if cur["running"] != "true" {
+			e = Event{Type: InterfaceDown, Interface: name, Message: "interface " + name + " not running"}
+		}
+		w.emit(e)
+	}
+}
+
+// routeName returns the name under which a route has been registered.
+func (m *Microtik) routeName(r Route) (string, bool) {
+
+	names := make([]string, 0, len(m.routes))
+	for name := range m.routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sel := m.routes[name]
+		if !sel.IsEmpty() && sel.Matches(r) {
+			return name, true
+		}
+	}
+	return "", false
+}
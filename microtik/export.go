@@ -0,0 +1,126 @@
+package microtik
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chunkSize is the amount of bytes read from a file on the router at once.
+const chunkSize = 32768
+
+// Export returns the configuration of the router as script (/export).
+// The script is written to a temporary file on the router, downloaded and
+// removed afterwards.
+func (m *Microtik) Export() (string, error) {
+
+	name := "infractl-export-" + time.Now().Format("20060102-150405")
+
+	// exporting the configuration may take several seconds
+	if _, err := m.runTimeout(m.timeout*3, "/export", "=file="+name); err != nil {
+		return "", err
+	}
+	defer m.removeFile(name + ".rsc")
+
+	data, err := m.readFile(name + ".rsc")
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// Backup creates an unencrypted binary backup of the router
+// (/system/backup/save) and downloads it. The backup can only be restored
+// on the same router model. It contains the passwords in plain text and
+// must be stored securely.
+func (m *Microtik) Backup() ([]byte, error) {
+
+	name := "infractl-backup-" + time.Now().Format("20060102-150405")
+
+	if _, err := m.runTimeout(m.timeout*3, "/system/backup/save", "=name="+name, "=dont-encrypt=yes"); err != nil {
+		return nil, err
+	}
+	defer m.removeFile(name + ".backup")
+
+	return m.readFile(name + ".backup")
+}
+
+// findFile returns the attributes of a file on the router. Depending on
+// the routerboard, files are located in a subdirectory (e.g. flash/).
+// Since the file may still be written, the lookup is repeated a few times.
+func (m *Microtik) findFile(name string) (map[string]string, error) {
+
+	for i := 0; i < 10; i++ {
+		reply, err := m.run("/file/print")
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range reply {
+			if f["name"] == name || strings.HasSuffix(f["name"], "/"+name) {
+				return f, nil
+			}
+		}
+		time.Sleep(time.Millisecond * 500)
+	}
+
+	return nil, fmt.Errorf("file %s not found on router", name)
+}
+
+// readFile downloads a file from the router. RouterOS v7 reads files in
+// chunks (/file/read). Older versions only provide the contents of small
+// files (< 4kB) through /file/print.
+func (m *Microtik) readFile(name string) ([]byte, error) {
+
+	f, err := m.findFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	path := f["name"]
+	size := atoi(strings.Replace(f["size"], " ", "", -1))
+
+	buf := bytes.Buffer{}
+
+	for {
+		reply, err := m.run("/file/read", "=file="+path,
+			"=offset="+strconv.Itoa(buf.Len()), "=chunk-size="+strconv.Itoa(chunkSize))
+		if err != nil {
+			if !isDeviceError(err) || buf.Len() > 0 {
+				return nil, err
+			}
+			// /file/read is not supported
+			contents, ok := f["contents"]
+			if !ok || len(contents) < size {
+				return nil, fmt.Errorf("unable to download %s: file too large for this RouterOS version", name)
+			}
+			return []byte(contents), nil
+		}
+
+		n := 0
+		for _, r := range reply {
+			n += len(r["data"])
+			buf.WriteString(r["data"])
+		}
+
+		if n < chunkSize {
+			break
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// removeFile deletes a file on the router.
+func (m *Microtik) removeFile(name string) error {
+
+	f, err := m.findFile(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.run("/file/remove", "=numbers="+f[".id"])
+	return err
+}
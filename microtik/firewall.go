@@ -0,0 +1,252 @@
+package microtik
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AddressListEntry is an entry of a firewall address list
+// (/ip/firewall/address-list).
+type AddressListEntry struct {
+	ID       string `json:"id"`
+	List     string `json:"list"`
+	Address  string `json:"address"`
+	Comment  string `json:"comment"`
+	Disabled bool   `json:"disabled"`
+	// Dynamic entries have been added with a timeout
+	Dynamic bool `json:"dynamic"`
+	// Timeout is the remaining time until the entry expires
+	Timeout time.Duration `json:"timeout"`
+}
+
+// AddressListEntries returns the entries of a firewall address list. If
+// list is empty, the entries of all lists are returned.
+func (m *Microtik) AddressListEntries(list string) ([]AddressListEntry, error) {
+
+	sentence := []string{"/ip/firewall/address-list/print"}
+	if len(list) > 0 {
+		sentence = append(sentence, "?list="+list)
+	}
+
+	reply, err := m.run(sentence...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]AddressListEntry, 0, len(reply))
+	for _, r := range reply {
+		e := AddressListEntry{
+			ID:       r[".id"],
+			List:     r["list"],
+			Address:  r["address"],
+			Comment:  r["comment"],
+			Disabled: r["disabled"] == "true",
+			Dynamic:  r["dynamic"] == "true",
+		}
+		if t, err := ParseDuration(r["timeout"]); err == nil {
+			e.Timeout = t
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// AddToAddressList adds an ip address or network (CIDR) to a firewall
+// address list, e.g. in order to block it. If timeout is not 0, the router
+// removes the entry after the timeout. The list must have been allowed
+// with the AddressList option.
+func (m *Microtik) AddToAddressList(list, address string, timeout time.Duration, comment string) error {
+
+	if err := m.checkAddressList(list, address); err != nil {
+		return err
+	}
+
+	sentence := []string{
+		"/ip/firewall/address-list/add",
+		"=list=" + list,
+		"=address=" + address,
+	}
+	if timeout > 0 {
+		sentence = append(sentence, "=timeout="+timeout.String())
+	}
+	if len(comment) > 0 {
+		sentence = append(sentence, "=comment="+comment)
+	}
+
+	_, err := m.run(sentence...)
+	return err
+}
+
+// RemoveFromAddressList removes an ip address or network from a firewall
+// address list. The list must have been allowed with the AddressList
+// option.
+func (m *Microtik) RemoveFromAddressList(list, address string) error {
+
+	if err := m.checkAddressList(list, address); err != nil {
+		return err
+	}
+
+	reply, err := m.run("/ip/firewall/address-list/print", "?list="+list, "?address="+address)
+	if err != nil {
+		return err
+	}
+
+	if len(reply) == 0 {
+		return fmt.Errorf("%w: %s in address list %s", ErrAddressNotFound, address, list)
+	}
+
+	for _, r := range reply {
+		if _, err := m.run("/ip/firewall/address-list/remove", "=numbers="+r[".id"]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAddressList verifies that the list may be modified and that the
+// address is a valid ip address or network.
+func (m *Microtik) checkAddressList(list, address string) error {
+
+	if !m.addressLists[list] {
+		return fmt.Errorf("%w: %s", ErrListNotAllowed, list)
+	}
+
+	if net.ParseIP(address) == nil {
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			return fmt.Errorf("%w %s", ErrInvalidAddress, address)
+		}
+	}
+
+	return nil
+}
+
+// NatRule is a registered NAT rule (/ip/firewall/nat).
+type NatRule struct {
+	Name        string `json:"name"`
+	ID          string `json:"id"`
+	Comment     string `json:"comment"`
+	Chain       string `json:"chain"`
+	Action      string `json:"action"`
+	Protocol    string `json:"protocol"`
+	DstPort     string `json:"dst_port"`
+	ToAddresses string `json:"to_addresses"`
+	ToPorts     string `json:"to_ports"`
+	Disabled    bool   `json:"disabled"`
+}
+
+// NatRules returns the registered NAT rules, sorted by their name.
+func (m *Microtik) NatRules() ([]NatRule, error) {
+
+	reply, err := m.run("/ip/firewall/nat/print")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(m.natRules))
+	for name := range m.natRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := []NatRule{}
+	for _, name := range names {
+		r, err := getNatRule(reply, m.natRules[name])
+		if err != nil {
+			return nil, fmt.Errorf("nat rule %s: %w", name, err)
+		}
+		rules = append(rules, parseNatRule(name, r))
+	}
+
+	return rules, nil
+}
+
+// EnableNatRule enables a registered NAT rule.
+func (m *Microtik) EnableNatRule(name string) error {
+	return m.setNatRule(name, false)
+}
+
+// DisableNatRule disables a registered NAT rule.
+func (m *Microtik) DisableNatRule(name string) error {
+	return m.setNatRule(name, true)
+}
+
+// setNatRule enables or disables a registered NAT rule and reads it back
+// in order to verify the modification.
+func (m *Microtik) setNatRule(name string, disabled bool) error {
+
+	name = strings.ToLower(name)
+
+	comment, ok := m.natRules[name]
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnknownNatRule, name)
+	}
+
+	reply, err := m.run("/ip/firewall/nat/print", "?comment="+comment)
+	if err != nil {
+		return err
+	}
+
+	r, err := getNatRule(reply, comment)
+	if err != nil {
+		return fmt.Errorf("nat rule %s: %w", name, err)
+	}
+
+	value := fmt.Sprint(disabled)
+
+	if _, err := m.run("/ip/firewall/nat/set", "=.id="+r[".id"], "=disabled="+value); err != nil {
+		return err
+	}
+
+	reply, err = m.run("/ip/firewall/nat/print", "?.id="+r[".id"])
+	if err != nil {
+		return fmt.Errorf("unable to verify the modification of nat rule %s: %w", name, err)
+	}
+
+	if len(reply) != 1 || reply[0]["disabled"] != value {
+		return fmt.Errorf("unable to verify the modification of nat rule %s", name)
+	}
+
+	return nil
+}
+
+// getNatRule returns the NAT rule with the given comment. Exactly one
+// rule must match.
+func getNatRule(reply []map[string]string, comment string) (map[string]string, error) {
+
+	var matches []map[string]string
+
+	for _, r := range reply {
+		if r["comment"] == comment {
+			matches = append(matches, r)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: no rule with comment %q", ErrNatRuleNotFound, comment)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %d rules with comment %q", ErrNatRuleNotFound, len(matches), comment)
+	}
+}
+
+func parseNatRule(name string, r map[string]string) NatRule {
+	return NatRule{
+		Name:        name,
+		ID:          r[".id"],
+		Comment:     r["comment"],
+		Chain:       r["chain"],
+		Action:      r["action"],
+		Protocol:    r["protocol"],
+		DstPort:     r["dst-port"],
+		ToAddresses: r["to-addresses"],
+		ToPorts:     r["to-ports"],
+		Disabled:    r["disabled"] == "true",
+	}
+}
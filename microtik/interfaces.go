@@ -0,0 +1,151 @@
+package microtik
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Interface contains the state and the counters of a network interface
+// (/interface) of the router.
+type Interface struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Comment   string `json:"comment"`
+	MTU       int    `json:"mtu"`
+	Running   bool   `json:"running"`
+	Disabled  bool   `json:"disabled"`
+	LinkSpeed string `json:"link_speed,omitempty"` // e.g. 1Gbps (ethernet only)
+	RxBytes   uint64 `json:"rx_bytes"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	TxPackets uint64 `json:"tx_packets"`
+	RxErrors  uint64 `json:"rx_errors"`
+	TxErrors  uint64 `json:"tx_errors"`
+	RxDrops   uint64 `json:"rx_drops"`
+	TxDrops   uint64 `json:"tx_drops"`
+}
+
+// Traffic contains the current throughput of an interface.
+type Traffic struct {
+	Interface          string `json:"interface"`
+	RxBitsPerSecond    uint64 `json:"rx_bps"`
+	TxBitsPerSecond    uint64 `json:"tx_bps"`
+	RxPacketsPerSecond uint64 `json:"rx_pps"`
+	TxPacketsPerSecond uint64 `json:"tx_pps"`
+}
+
+// Interfaces returns all network interfaces of the router with their state
+// and counters. For running ethernet interfaces, the negotiated link speed
+// is determined as well.
+func (m *Microtik) Interfaces() ([]Interface, error) {
+
+	reply, err := m.run("/interface/print")
+	if err != nil {
+		return nil, err
+	}
+
+	ifaces := make([]Interface, 0, len(reply))
+	ethernet := []string{}
+
+	for _, r := range reply {
+		iface := Interface{
+			ID:        r[".id"],
+			Name:      r["name"],
+			Type:      r["type"],
+			Comment:   r["comment"],
+			MTU:       atoi(r["actual-mtu"]),
+			Running:   r["running"] == "true",
+			Disabled:  r["disabled"] == "true",
+			RxBytes:   atou(r["rx-byte"]),
+			TxBytes:   atou(r["tx-byte"]),
+			RxPackets: atou(r["rx-packet"]),
+			TxPackets: atou(r["tx-packet"]),
+			RxErrors:  atou(r["rx-error"]),
+			TxErrors:  atou(r["tx-error"]),
+			RxDrops:   atou(r["rx-drop"]),
+			TxDrops:   atou(r["tx-drop"]),
+		}
+		if iface.Type == "ether" && iface.Running {
+			ethernet = append(ethernet, iface.Name)
+		}
+		ifaces = append(ifaces, iface)
+	}
+
+	if len(ethernet) == 0 {
+		return ifaces, nil
+	}
+
+	rates, err := m.run("/interface/ethernet/monitor",
+		"=numbers="+strings.Join(ethernet, ","), "=once=")
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine the link speed: %w", err)
+	}
+
+	for _, r := range rates {
+		for i := range ifaces {
+			if ifaces[i].Name == r["name"] {
+				ifaces[i].LinkSpeed = r["rate"]
+			}
+		}
+	}
+
+	return ifaces, nil
+}
+
+// InterfaceRunning returns true if the network interface (e.g. lte1) of
+// the router is running.
+func (m *Microtik) InterfaceRunning(name string) (bool, error) {
+
+	reply, err := m.run("/interface/print", "?name="+name)
+	if err != nil {
+		return false, err
+	}
+
+	if len(reply) == 0 {
+		return false, fmt.Errorf("unknown interface %s", name)
+	}
+
+	return reply[0]["running"] == "true", nil
+}
+
+// HasInterface returns true if the network interface exists on the
+// router. USB devices like LTE modems only show up once they have been
+// enumerated.
+func (m *Microtik) HasInterface(name string) (bool, error) {
+
+	reply, err := m.run("/interface/print", "?name="+name)
+	if err != nil {
+		return false, err
+	}
+
+	return len(reply) > 0, nil
+}
+
+// MonitorTraffic samples the current throughput of one or more interfaces
+// (/interface/monitor-traffic).
+func (m *Microtik) MonitorTraffic(ifaces ...string) ([]Traffic, error) {
+
+	if len(ifaces) == 0 {
+		return nil, fmt.Errorf("no interface provided")
+	}
+
+	reply, err := m.run("/interface/monitor-traffic",
+		"=interface="+strings.Join(ifaces, ","), "=once=")
+	if err != nil {
+		return nil, err
+	}
+
+	traffic := make([]Traffic, 0, len(reply))
+	for _, r := range reply {
+		traffic = append(traffic, Traffic{
+			Interface:          r["name"],
+			RxBitsPerSecond:    atou(r["rx-bits-per-second"]),
+			TxBitsPerSecond:    atou(r["tx-bits-per-second"]),
+			RxPacketsPerSecond: atou(r["rx-packets-per-second"]),
+			TxPacketsPerSecond: atou(r["tx-packets-per-second"]),
+		})
+	}
+
+	return traffic, nil
+}
@@ -0,0 +1,80 @@
+package microtik
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LTEInfo contains the radio and registration status of an LTE interface
+// (/interface/lte/info) of the router. Signal levels which are not
+// reported by the modem are nil.
+type LTEInfo struct {
+	Interface          string        `json:"interface"`
+	RegistrationStatus string        `json:"registration_status"`
+	Registered         bool          `json:"registered"`
+	Operator           string        `json:"operator"`
+	AccessTechnology   string        `json:"access_technology"`
+	CellID             string        `json:"cell_id"`
+	Band               string        `json:"band"`
+	RSSI               *float64      `json:"rssi,omitempty"` // dBm
+	RSRP               *float64      `json:"rsrp,omitempty"` // dBm
+	RSRQ               *float64      `json:"rsrq,omitempty"` // dB
+	SINR               *float64      `json:"sinr,omitempty"` // dB
+	SessionUptime      time.Duration `json:"session_uptime"`
+	IMEI               string        `json:"imei"`
+}
+
+// LTEInfo retrieves the radio and registration status of the LTE interface
+// (e.g. lte1) of the router. RouterOS v7 renamed /interface/lte/info to
+// /interface/lte/monitor, which is used as a fallback.
+func (m *Microtik) LTEInfo(iface string) (LTEInfo, error) {
+
+	reply, err := m.run("/interface/lte/info", "=number="+iface, "=once=")
+	if err != nil && isDeviceError(err) {
+		reply, err = m.run("/interface/lte/monitor", "=numbers="+iface, "=once=")
+	}
+	if err != nil {
+		return LTEInfo{}, err
+	}
+
+	info := LTEInfo{Interface: iface}
+
+	if len(reply) == 0 {
+		return info, nil
+	}
+
+	r := reply[0]
+
+	info.RegistrationStatus = r["registration-status"]
+	info.Registered = info.RegistrationStatus == "registered"
+	info.Operator = r["current-operator"]
+	info.AccessTechnology = r["access-technology"]
+	info.CellID = r["current-cellid"]
+	info.Band = r["primary-band"]
+	info.RSSI = level(r["rssi"])
+	info.RSRP = level(r["rsrp"])
+	info.RSRQ = level(r["rsrq"])
+	info.SINR = level(r["sinr"])
+	info.IMEI = r["imei"]
+
+	if uptime, err := ParseDuration(r["session-uptime"]); err == nil {
+		info.SessionUptime = uptime
+	}
+
+	return info, nil
+}
+
+// level parses a signal level with its unit (e.g. "-95dBm").
+func level(s string) *float64 {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && strings.ContainsRune("+-.0123456789", rune(s[end])) {
+		end++
+	}
+	f, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
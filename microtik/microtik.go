@@ -1,18 +1,47 @@
 package microtik
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
+	"log"
 	"strings"
-
-	"gopkg.in/routeros.v2"
+	"sync"
+	"time"
 )
 
 // Microtik is a struct holding the parameters and the connection to a
-// microtik device.
+// microtik device. The connection is established lazily on the first
+// request and kept open afterwards. If the connection breaks, it will
+// be re-established (with an exponential backoff) on the next request.
+// All methods are safe for concurrent use.
 type Microtik struct {
-	*routeros.Client
-	config   Config
-	routeIDs map[string]string
+	sync.Mutex
+	transport transport
+	config    Config
+	routes    map[string]RouteSelector
+	// attributes of the routes which may be modified
+	routeAttributes map[string]bool
+	// firewall address lists which may be modified
+	addressLists map[string]bool
+	// registered NAT rules (name -> comment)
+	natRules      map[string]string
+	timeout       time.Duration
+	resetDuration time.Duration
+	keepAlive     time.Duration
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	backoff       time.Duration
+	nextDial      time.Time
+	// dialErr is the error of the last failed connection attempt
+	dialErr error
+	closed  bool
+	closeCh chan struct{}
+	// txMu protects txRunning, which is set while a transaction
+	// modifies the routes
+	txMu      sync.Mutex
+	txRunning bool
 }
 
 // Config is a struct which contains the configuration parameters
@@ -22,6 +51,24 @@ type Config struct {
 	Port     int
 	Username string
 	Password string
+	// Transport selects the protocol used to communicate with the router:
+	// TransportAPI (default) or TransportREST
+	Transport string
+	// TLS enables the encrypted API service (api-ssl, default port 8729).
+	// The REST API is always encrypted.
+	TLS bool
+	// CAFile contains the CA certificate(s) against which the router's
+	// certificate is verified. If empty, the system's CAs are used.
+	CAFile string
+	// ServerName overrides the name which is expected in the router's
+	// certificate. Defaults to Address.
+	ServerName string
+	// InsecureSkipVerify disables the verification of the router's
+	// certificate. Only use this for testing.
+	InsecureSkipVerify bool
+	// CertFile and KeyFile contain an optional client certificate
+	CertFile string
+	KeyFile  string
 }
 
 // RouteResult is type used to return route results.
@@ -31,53 +78,239 @@ type RouteResult map[string]bool
 type Option func(m *Microtik)
 
 // New is a constructor method and returns an initalized (but not yet connected)
-// instance of a Microtik object
+// instance of a Microtik object. If a keepalive interval has been set,
+// a background routine will periodically probe the connection until
+// Close is called.
 func New(c Config, opts ...Option) *Microtik {
 
 	m := &Microtik{
-		config:   c,
-		routeIDs: make(map[string]string),
+		config: c,
+		routes: make(map[string]RouteSelector),
+		routeAttributes: map[string]bool{
+			"disabled": true,
+		},
+		addressLists:  make(map[string]bool),
+		natRules:      make(map[string]string),
+		timeout:       time.Second * 10,
+		resetDuration: time.Second * 5,
+		minBackoff:    time.Second,
+		maxBackoff:    time.Minute,
+		closeCh:       make(chan struct{}),
 	}
 
 	for _, opt := range opts {
 		opt(m)
 	}
 
+	if m.keepAlive > 0 {
+		go m.startKeepAlive(m.keepAlive)
+	}
+
 	return m
 }
 
+// Close terminates the connection to the router and stops the keepalive
+// routine. The Microtik object can not be used anymore afterwards.
+func (m *Microtik) Close() {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.closed {
+		return
+	}
+	m.closed = true
+	close(m.closeCh)
+	m.disconnect()
+}
+
+// connect dials the router and logs in, unless a session is already
+// established. After a failed attempt, further attempts are rejected until
+// the backoff period has expired. The caller must hold the lock.
 func (m *Microtik) connect() error {
-	url := fmt.Sprintf("%s:%d", m.config.Address, m.config.Port)
-	c, err := routeros.Dial(url, m.config.Username, m.config.Password)
+
+	if m.closed {
+		return fmt.Errorf("connection to router %s closed", m.config.Address)
+	}
+
+	if m.transport != nil {
+		return nil
+	}
+
+	// report the cause of the last attempt, which might not have been
+	// a network problem (e.g. wrong credentials)
+	if wait := time.Until(m.nextDial); wait > 0 && m.dialErr != nil {
+		return fmt.Errorf("%w, next connection attempt in %v", m.dialErr, wait.Round(time.Second))
+	}
+
+	t, err := dial(m.config, m.timeout)
 	if err != nil {
+		m.dialErr = err
+		m.increaseBackoff()
 		return err
 	}
-	m.Client = c
+
+	m.transport = t
+	m.backoff = 0
+	m.nextDial = time.Time{}
+	m.dialErr = nil
 	return nil
 }
 
-// Reset4G cuts the power of a USB LTE/4G modem connected to the routerboard
-// for a period of 5 seconds.
-func (m *Microtik) Reset4G() error {
+// tlsConfig returns the TLS configuration for the api-ssl service.
+func (c Config) tlsConfig() (*tls.Config, error) {
+
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if len(tlsConfig.ServerName) == 0 {
+		tlsConfig.ServerName = c.Address
+	}
+
+	if len(c.CAFile) > 0 {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(c.CertFile) > 0 || len(c.KeyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// disconnect closes the current session (if any). The caller must hold
+// the lock.
+func (m *Microtik) disconnect() {
+	if m.transport == nil {
+		return
+	}
+	m.transport.close()
+	m.transport = nil
+}
+
+// increaseBackoff doubles the time to wait before the next connection
+// attempt. The caller must hold the lock.
+func (m *Microtik) increaseBackoff() {
+	switch {
+	case m.backoff == 0:
+		m.backoff = m.minBackoff
+	case m.backoff*2 > m.maxBackoff:
+		m.backoff = m.maxBackoff
+	default:
+		m.backoff = m.backoff * 2
+	}
+	m.nextDial = time.Now().Add(m.backoff)
+}
+
+// run executes a command on the router and returns its reply. If no
+// session exists, a new one will be established. Errors reported by the
+// router itself leave the session intact, while any other error (e.g.
+// a broken connection or a timeout) closes it, so that the next call
+// reconnects. Commands are never repeated automatically since they
+// might not be idempotent.
+func (m *Microtik) run(sentence ...string) ([]map[string]string, error) {
+	return m.runTimeout(m.timeout, sentence...)
+}
+
+// runTimeout executes a command like run, but with a custom timeout for
+// commands which take longer to complete (e.g. /ping).
+func (m *Microtik) runTimeout(timeout time.Duration, sentence ...string) ([]map[string]string, error) {
+	m.Lock()
+	defer m.Unlock()
 
 	if err := m.connect(); err != nil {
-		return err
+		return nil, err
 	}
 
-	reply, err := m.Run("/system/routerboard/usb/power-reset", "?duration=5s")
+	reply, err := m.transport.run(timeout, sentence...)
+	if err != nil {
+		if !isDeviceError(err) {
+			m.disconnect()
+		}
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// startKeepAlive periodically sends a lightweight command to the router
+// in order to detect broken connections early and to reconnect in the
+// background. It returns when Close is called.
+func (m *Microtik) startKeepAlive(interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.closeCh:
+			return
+		case <-ticker.C:
+			if _, err := m.run("/system/identity/print"); err != nil {
+				log.Printf("keepalive %s: %v\n", m.config.Address, err)
+			}
+		}
+	}
+}
+
+// Reset4G cuts the power of a USB LTE/4G modem connected to the routerboard
+// for the period set with the ResetDuration option (default: 5 seconds).
+func (m *Microtik) Reset4G() error {
+
+	cmd := "/system/routerboard/usb/power-reset"
+
+	reply, err := m.run(cmd, "=duration="+m.resetDuration.String())
 	if err != nil {
 		return err
 	}
 
-	if len(reply.Re) > 0 {
-		return fmt.Errorf("Error: %v", reply)
+	// the command doesn't return anything on success
+	if len(reply) > 0 {
+		msgs := []string{}
+		for _, r := range reply {
+			if msg, ok := r["message"]; ok {
+				msgs = append(msgs, msg)
+			}
+		}
+		if len(msgs) == 0 {
+			return fmt.Errorf("unexpected reply to %s: %v", cmd, reply)
+		}
+		return &TrapError{Command: cmd, Message: strings.Join(msgs, "; ")}
 	}
 
-	m.Client.Close()
-	m.Client = nil
 	return nil
 }
 
+// DHCPClientStatus returns the status (e.g. bound, searching...) of the DHCP
+// client on the interface. It returns an empty string if no DHCP client
+// is configured on the interface.
+func (m *Microtik) DHCPClientStatus(iface string) (string, error) {
+
+	reply, err := m.run("/ip/dhcp-client/print", "?interface="+iface)
+	if err != nil {
+		return "", err
+	}
+
+	if len(reply) == 0 {
+		return "", nil
+	}
+
+	return reply[0]["status"], nil
+}
+
 // RouteStatus allows to query this status of a particular route on a
 // microtik device (ip/route). The corresponding route must be registered during
 // construction of the Microtik object, otherwise this method will fail.
@@ -89,26 +322,11 @@ func (m *Microtik) RouteStatus(name string) (RouteResult, error) {
 
 	name = strings.ToLower(name)
 
-	rComment, ok := m.routeIDs[name]
-	if !ok {
-		return nil, fmt.Errorf("unknown route %s", name)
-	}
-
-	if err := m.connect(); err != nil {
-		return nil, err
-	}
-
-	reply, err := m.Run("/ip/route/print")
+	route, err := m.findRoute(name)
 	if err != nil {
 		return nil, err
 	}
 
-	route, err := getRoute(reply, rComment)
-
-	if route == nil {
-		return nil, fmt.Errorf("unable to find route %s", name)
-	}
-
 	disabled := false
 	d, ok := route["disabled"]
 	if !ok {
@@ -134,76 +352,3 @@ func (m *Microtik) RouteStatus(name string) (RouteResult, error) {
 
 	return res, nil
 }
-
-// SetRoute allows to set parameters for a particular route on a microtik
-// device (ip/route). The corresponding route must be registered during
-// construction of the Microtik object, otherwise this method will fail.
-func (m *Microtik) SetRoute(name, command string) error {
-
-	name = strings.ToLower(name)
-
-	rComment, ok := m.routeIDs[name]
-	if !ok {
-		return fmt.Errorf("unknown route %s", name)
-	}
-
-	if err := m.connect(); err != nil {
-		return err
-	}
-
-	reply, err := m.Run("/ip/route/print")
-	if err != nil {
-		return err
-	}
-
-	route, err := getRoute(reply, rComment)
-
-	if route == nil {
-		return fmt.Errorf("unable to find route %s", name)
-	}
-
-	nr, ok := route[".id"]
-	if !ok {
-		return fmt.Errorf("unable to determine the id of route %s", name)
-	}
-
-	query := []string{"/ip/route/set", "=.id=" + nr, "=" + command}
-
-	reply, err = m.RunArgs(query)
-
-	if err != nil {
-		return err
-	}
-
-	// fmt.Println("reply:", reply)
-
-	return nil
-}
-
-// getRoute retrieves the parameters for a route from a routeros.Reply
-func getRoute(reply *routeros.Reply, comment string) (map[string]string, error) {
-
-	if reply == nil {
-		return nil, fmt.Errorf("router response nil")
-	}
-
-	if len(reply.Re) == 0 {
-		return nil, fmt.Errorf("router response empty")
-	}
-
-	var route map[string]string
-
-	for _, r := range reply.Re {
-		c, ok := r.Map["comment"]
-		if !ok {
-			continue
-		}
-		// the only way to identify a route
-		// is comparing it's comment
-		if c == comment {
-			route = r.Map
-			break
-		}
-	}
-	return route, nil
-}
@@ -0,0 +1,185 @@
+package microtik
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik/routerostest"
+)
+
+// newTestMicrotik returns a Microtik connected to the fake router. It is
+// closed at the end of the test.
+func newTestMicrotik(t *testing.T, srv *routerostest.Server, opts ...Option) *Microtik {
+	t.Helper()
+
+	c := Config{
+		Address:  srv.Host(),
+		Port:     srv.Port(),
+		Username: "admin",
+	}
+
+	m := New(c, append([]Option{Timeout(time.Second)}, opts...)...)
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+func TestReset4G(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, ResetDuration(time.Second*3))
+
+	if err := m.Reset4G(); err != nil {
+		t.Fatal(err)
+	}
+
+	if resets := srv.PowerResets(); len(resets) != 1 || resets[0] != "3s" {
+		t.Errorf("got power resets %v, expected [3s]", resets)
+	}
+}
+
+func TestReset4GTrap(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	defer srv.Close()
+
+	srv.Fail("/system/routerboard/usb/power-reset", "no such command prefix")
+
+	m := newTestMicrotik(t, srv)
+
+	err := m.Reset4G()
+	if err == nil || !strings.Contains(err.Error(), "no such command prefix") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// errors reported by the router leave the session intact
+	reply, err := m.run("/system/identity/print")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply) != 1 || reply[0]["name"] != "MikroTik" {
+		t.Errorf("unexpected reply %v", reply)
+	}
+	if srv.Logins() != 1 {
+		t.Errorf("got %d logins, expected 1", srv.Logins())
+	}
+}
+
+func TestReset4GUnexpectedReply(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("/system/routerboard/usb/power-reset", func(routerostest.Command) ([]map[string]string, error) {
+		return []map[string]string{{"message": "usb port not found"}}, nil
+	})
+
+	m := newTestMicrotik(t, srv)
+
+	var trapErr *TrapError
+	if err := m.Reset4G(); !errors.As(err, &trapErr) || trapErr.Message != "usb port not found" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		opts     []routerostest.Option
+		password string
+		ok       bool
+	}{
+		{"valid", []routerostest.Option{routerostest.Credentials("admin", "secret")}, "secret", true},
+		{"invalid", []routerostest.Option{routerostest.Credentials("admin", "secret")}, "wrong", false},
+		{"legacy", []routerostest.Option{routerostest.Credentials("admin", "secret"), routerostest.LegacyLogin()}, "secret", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := routerostest.NewServer(tc.opts...)
+			defer srv.Close()
+
+			m := newTestMicrotik(t, srv)
+			m.config.Password = tc.password
+
+			_, err := m.run("/system/identity/print")
+			if tc.ok != (err == nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, Timeout(time.Millisecond*100))
+
+	srv.Delay("/system/identity/print", time.Millisecond*300)
+
+	start := time.Now()
+	if _, err := m.run("/system/identity/print"); err == nil {
+		t.Fatal("expected a timeout")
+	}
+	if d := time.Since(start); d > time.Millisecond*250 {
+		t.Errorf("command aborted after %v", d)
+	}
+
+	// the broken session is replaced on the next command
+	srv.ClearFaults()
+	if _, err := m.run("/system/identity/print"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Logins() != 2 {
+		t.Errorf("got %d logins, expected 2", srv.Logins())
+	}
+}
+
+func TestReconnect(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv)
+
+	if _, err := m.run("/system/identity/print"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Hangup("/system/identity/print")
+	if _, err := m.run("/system/identity/print"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	srv.ClearFaults()
+	if _, err := m.run("/system/identity/print"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Logins() != 2 {
+		t.Errorf("got %d logins, expected 2", srv.Logins())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	m := newTestMicrotik(t, srv, Backoff(time.Minute, time.Minute))
+	srv.Close()
+
+	if _, err := m.run("/system/identity/print"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// further attempts are rejected without dialing
+	_, err := m.run("/system/identity/print")
+	if err == nil || !strings.Contains(err.Error(), "next connection attempt") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package microtik
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 // RouteID is a functional option. It is used when working with ip/routes
 // on microtik router. In order to work with routes there needs to be a
@@ -9,8 +12,87 @@ import "strings"
 // arbitrary name and a string matching the route's comment in the microtik
 // router.
 func RouteID(name, comment string) Option {
+	return RouteMatch(name, RouteSelector{Comment: comment})
+}
+
+// RouteMatch is a functional option which registers a route with an
+// arbitrary name. In contrast to RouteID, the route can be identified by
+// any combination of its comment (exact or regular expression),
+// destination address, gateway, routing mark and RouterOS id. The selector
+// must match exactly one route.
+func RouteMatch(name string, sel RouteSelector) Option {
 	return func(m *Microtik) {
 		name = strings.ToLower(name)
-		m.routeIDs[name] = comment
+		m.routes[name] = sel
+	}
+}
+
+// Timeout is a functional option which sets the maximum duration for
+// establishing a connection and for each request to the router.
+func Timeout(d time.Duration) Option {
+	return func(m *Microtik) {
+		m.timeout = d
+	}
+}
+
+// ResetDuration is a functional option which sets the period during which
+// the power of the USB port is cut by Reset4G.
+func ResetDuration(d time.Duration) Option {
+	return func(m *Microtik) {
+		m.resetDuration = d
+	}
+}
+
+// KeepAlive is a functional option which enables a background routine
+// probing the connection to the router in the given interval. Broken
+// connections are detected and re-established without waiting for the
+// next request. A value of 0 disables the keepalive.
+func KeepAlive(interval time.Duration) Option {
+	return func(m *Microtik) {
+		m.keepAlive = interval
+	}
+}
+
+// Backoff is a functional option which sets the minimum and maximum time
+// to wait between failed connection attempts. The waiting time is doubled
+// after each failed attempt.
+func Backoff(min, max time.Duration) Option {
+	return func(m *Microtik) {
+		m.minBackoff = min
+		m.maxBackoff = max
+	}
+}
+
+// RouteAttributes is a functional option which sets the whitelist of route
+// attributes (e.g. "disabled", "distance", "gateway", "check-gateway",
+// "comment") which may be modified through UpdateRoute. By default, only
+// "disabled" may be modified.
+func RouteAttributes(attributes ...string) Option {
+	return func(m *Microtik) {
+		m.routeAttributes = make(map[string]bool)
+		for _, a := range attributes {
+			m.routeAttributes[strings.ToLower(a)] = true
+		}
+	}
+}
+
+// AddressList is a functional option which allows adding entries to and
+// removing entries from a firewall address list (/ip/firewall/address-list).
+// Entries of other lists can only be read.
+func AddressList(lists ...string) Option {
+	return func(m *Microtik) {
+		for _, l := range lists {
+			m.addressLists[l] = true
+		}
+	}
+}
+
+// NatRuleID is a functional option which registers a NAT rule
+// (/ip/firewall/nat) with an arbitrary name, similar to RouteID. The rule
+// is identified by its comment. Only registered rules can be enabled or
+// disabled.
+func NatRuleID(name, comment string) Option {
+	return func(m *Microtik) {
+		m.natRules[strings.ToLower(name)] = comment
 	}
 }
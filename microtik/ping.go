@@ -0,0 +1,185 @@
+package microtik
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dh1tw/infractl/connectivity"
+)
+
+// PingOptions determine how the router sends pings and traceroutes. By
+// selecting the source address, the outgoing interface or the routing
+// table, each uplink can be tested independently of the currently active
+// route. Empty fields are omitted and the router's defaults apply.
+type PingOptions struct {
+	// Count is the amount of pings (default: 3) or the amount of probes
+	// per hop of a traceroute (default: 1)
+	Count int
+	// Interval between two pings (default: 1s)
+	Interval time.Duration
+	// MaxHops limits the amount of hops of a traceroute (default: 30)
+	MaxHops      int
+	SrcAddress   string
+	Interface    string
+	RoutingTable string
+}
+
+// args returns the attribute words for the options which are shared by
+// /ping and /tool/traceroute.
+func (o PingOptions) args() []string {
+	args := []string{}
+	if len(o.SrcAddress) > 0 {
+		args = append(args, "=src-address="+o.SrcAddress)
+	}
+	if len(o.Interface) > 0 {
+		args = append(args, "=interface="+o.Interface)
+	}
+	if len(o.RoutingTable) > 0 {
+		args = append(args, "=routing-table="+o.RoutingTable)
+	}
+	return args
+}
+
+// Ping sends pings from the router (/ping) to the target and returns the
+// packet loss and the min/avg/max round trip time. If no reply has been
+// received, the result is marked as failed, but no error is returned.
+func (m *Microtik) Ping(target string, opts PingOptions) (connectivity.PingResult, error) {
+
+	if opts.Count <= 0 {
+		opts.Count = 3
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+
+	pr := connectivity.PingResult{
+		Address: target,
+		Loss:    100,
+		Failed:  true,
+	}
+
+	sentence := append([]string{
+		"/ping",
+		"=address=" + target,
+		"=count=" + strconv.Itoa(opts.Count),
+		"=interval=" + strconv.FormatInt(opts.Interval.Milliseconds(), 10) + "ms",
+	}, opts.args()...)
+
+	// the router replies after all pings have been sent
+	timeout := m.timeout + time.Duration(opts.Count)*opts.Interval
+
+	reply, err := m.runTimeout(timeout, sentence...)
+	if err != nil {
+		return pr, err
+	}
+
+	// every reply contains the statistics of all pings sent so far
+	if len(reply) == 0 {
+		return pr, fmt.Errorf("no ping statistics received for %s", target)
+	}
+	r := reply[len(reply)-1]
+
+	sent := atoi(r["sent"])
+	received := atoi(r["received"])
+	if sent == 0 {
+		return pr, fmt.Errorf("no pings sent to %s", target)
+	}
+
+	pr.Loss = float64(sent-received) / float64(sent) * 100
+	if received == 0 {
+		return pr, nil
+	}
+
+	pr.Failed = false
+	pr.RTT = rtt(r["avg-rtt"])
+	pr.MinRTT = rtt(r["min-rtt"])
+	pr.MaxRTT = rtt(r["max-rtt"])
+
+	return pr, nil
+}
+
+// TracerouteHop contains the statistics of a single hop of a traceroute.
+// The Address is empty if the hop didn't reply.
+type TracerouteHop struct {
+	Hop     int           `json:"hop"`
+	Address string        `json:"address"`
+	Sent    int           `json:"sent"`
+	Loss    float64       `json:"loss"` // packet loss in percent
+	Last    time.Duration `json:"last"`
+	RTT     time.Duration `json:"rtt"` // average round trip time
+	MinRTT  time.Duration `json:"min_rtt"`
+	MaxRTT  time.Duration `json:"max_rtt"`
+	Status  string        `json:"status,omitempty"`
+}
+
+// Traceroute determines the path from the router (/tool/traceroute) to
+// the target.
+func (m *Microtik) Traceroute(target string, opts PingOptions) ([]TracerouteHop, error) {
+
+	if opts.Count <= 0 {
+		opts.Count = 1
+	}
+	if opts.MaxHops <= 0 {
+		opts.MaxHops = 30
+	}
+
+	sentence := append([]string{
+		"/tool/traceroute",
+		"=address=" + target,
+		"=count=" + strconv.Itoa(opts.Count),
+		"=max-hops=" + strconv.Itoa(opts.MaxHops),
+		"=use-dns=no",
+	}, opts.args()...)
+
+	// a probe times out after 1s if the hop doesn't reply
+	timeout := m.timeout + time.Duration(opts.Count*opts.MaxHops)*time.Second
+
+	reply, err := m.runTimeout(timeout, sentence...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the router repeats the complete table after each round of probes
+	// in a new section. Only the last (complete) section is relevant.
+	hops := []TracerouteHop{}
+	section := ""
+
+	for _, r := range reply {
+		if s, ok := r[".section"]; ok && s != section {
+			section = s
+			hops = hops[:0]
+		}
+
+		hop := TracerouteHop{
+			Hop:     len(hops) + 1,
+			Address: r["address"],
+			Sent:    atoi(r["sent"]),
+			Last:    rtt(r["last"]),
+			RTT:     rtt(r["avg"]),
+			MinRTT:  rtt(r["best"]),
+			MaxRTT:  rtt(r["worst"]),
+			Status:  r["status"],
+		}
+		if loss := level(r["loss"]); loss != nil {
+			hop.Loss = *loss
+		}
+
+		hops = append(hops, hop)
+	}
+
+	return hops, nil
+}
+
+// rtt parses a round trip time. The router reports it either as a duration
+// (e.g. "12ms345us") or in milliseconds (e.g. "12.3"). Missing values and
+// timeouts result in 0.
+func rtt(s string) time.Duration {
+	if d, err := ParseDuration(s); err == nil {
+		return d
+	}
+	if ms, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	return 0
+}
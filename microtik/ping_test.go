@@ -0,0 +1,82 @@
+package microtik
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik/routerostest"
+)
+
+func TestPing(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	defer srv.Close()
+
+	var args map[string]string
+	srv.Handle("/ping", func(cmd routerostest.Command) ([]map[string]string, error) {
+		args = cmd.Args
+		return []map[string]string{
+			{"seq": "0", "sent": "1", "received": "1"},
+			{"seq": "1", "sent": "2", "received": "1", "min-rtt": "10ms", "avg-rtt": "15ms", "max-rtt": "20ms"},
+		}, nil
+	})
+
+	m := newTestMicrotik(t, srv)
+
+	opts := PingOptions{Count: 2, Interval: time.Second * 2 / 3, Interface: "lte1"}
+	res, err := m.Ping("1.1.1.1", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the router expects the interval in its own duration syntax
+	exp := map[string]string{"address": "1.1.1.1", "count": "2", "interval": "666ms", "interface": "lte1"}
+	for k, v := range exp {
+		if args[k] != v {
+			t.Errorf("%s: got %q, expected %q", k, args[k], v)
+		}
+	}
+
+	if res.Failed || res.Loss != 50 || res.RTT != 15*time.Millisecond ||
+		res.MinRTT != 10*time.Millisecond || res.MaxRTT != 20*time.Millisecond {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestTraceroute(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	defer srv.Close()
+
+	var args map[string]string
+	srv.Handle("/tool/traceroute", func(cmd routerostest.Command) ([]map[string]string, error) {
+		args = cmd.Args
+		return []map[string]string{
+			{".section": "0", "address": "10.0.0.1", "sent": "1", "loss": "0%", "last": "1ms"},
+			{".section": "1", "address": "10.0.0.1", "sent": "2", "loss": "0%", "avg": "1.5"},
+			{".section": "1", "address": "", "sent": "2", "loss": "100%", "status": "timeout"},
+		}, nil
+	})
+
+	m := newTestMicrotik(t, srv)
+
+	hops, err := m.Traceroute("1.1.1.1", PingOptions{Count: 2, MaxHops: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if args["count"] != "2" || args["max-hops"] != "5" {
+		t.Errorf("unexpected arguments %v", args)
+	}
+
+	// only the last section is returned
+	if len(hops) != 2 {
+		t.Fatalf("got %d hops, expected 2", len(hops))
+	}
+	if hops[0].Hop != 1 || hops[0].RTT != 1500*time.Microsecond {
+		t.Errorf("unexpected first hop %+v", hops[0])
+	}
+	if hops[1].Hop != 2 || hops[1].Loss != 100 || hops[1].Status != "timeout" {
+		t.Errorf("unexpected second hop %+v", hops[1])
+	}
+}
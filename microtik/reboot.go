@@ -0,0 +1,106 @@
+package microtik
+
+import (
+	"fmt"
+	"time"
+)
+
+// rebootPollInterval is the interval in which the router is probed while
+// it reboots.
+const rebootPollInterval = time.Second * 2
+
+// RebootResult describes the course of a reboot of the router.
+type RebootResult struct {
+	Started time.Time `json:"started"`
+	// Down is the time when the router became unreachable
+	Down time.Time `json:"down"`
+	// Up is the time when the API of the router became reachable again
+	Up       time.Time     `json:"up"`
+	Downtime time.Duration `json:"downtime"`
+}
+
+// Reboot reboots the router (/system/reboot) and waits until its API is
+// reachable again, but at most for the given timeout. The router is
+// considered back once it accepts logins and its uptime shows that it has
+// been restarted. The current session is closed; it will be
+// re-established on the next request.
+func (m *Microtik) Reboot(timeout time.Duration) (RebootResult, error) {
+
+	res := RebootResult{Started: time.Now()}
+
+	// make sure the reboot command can be sent
+	if _, err := m.run("/system/identity/print"); err != nil {
+		return res, err
+	}
+
+	// the router might close the connection before replying
+	if _, err := m.run("/system/reboot"); err != nil && isDeviceError(err) {
+		return res, err
+	}
+
+	m.Lock()
+	m.disconnect()
+	m.Unlock()
+
+	deadline := res.Started.Add(timeout)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(rebootPollInterval)
+
+		uptime, err := m.probeUptime()
+		if err != nil {
+			if res.Down.IsZero() {
+				res.Down = time.Now()
+			}
+			continue
+		}
+
+		// still shutting down
+		if uptime >= time.Since(res.Started) {
+			continue
+		}
+
+		res.Up = time.Now()
+		if res.Down.IsZero() {
+			// the router went down and came back between two probes
+			res.Down = res.Up.Add(-uptime)
+		}
+		res.Downtime = res.Up.Sub(res.Down)
+
+		// allow reconnecting immediately
+		m.Lock()
+		m.backoff = 0
+		m.nextDial = time.Time{}
+		m.Unlock()
+
+		return res, nil
+	}
+
+	if res.Down.IsZero() {
+		return res, fmt.Errorf("router %s did not reboot within %v", m.config.Address, timeout)
+	}
+
+	return res, fmt.Errorf("%w (%s) %v after the reboot", ErrUnreachable, m.config.Address, timeout)
+}
+
+// probeUptime logs into the router through a new session and returns its
+// uptime.
+func (m *Microtik) probeUptime() (time.Duration, error) {
+
+	t, err := dial(m.config, m.timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer t.close()
+
+	reply, err := t.run(m.timeout, "/system/resource/print")
+	if err != nil {
+		return 0, err
+	}
+
+	if len(reply) == 0 {
+		return 0, fmt.Errorf("empty reply")
+	}
+
+	return ParseDuration(reply[0]["uptime"])
+}
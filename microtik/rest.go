@@ -0,0 +1,170 @@
+package microtik
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// restTransport uses the REST API of RouterOS v7, which is served by the
+// www-ssl service. Every API sentence is translated into a POST request on
+// the path of the command (e.g. /rest/ip/route/print). Attribute words
+// ("=name=value") become fields of the JSON body and query words
+// ("?name=value") are passed in the ".query" field.
+type restTransport struct {
+	client   *http.Client
+	url      string
+	username string
+	password string
+}
+
+// restError is the reply of the router if it rejected a request.
+type restError struct {
+	Status  int    `json:"error"`
+	Message string `json:"message"`
+	Detail  string `json:"detail"`
+}
+
+func (e *restError) String() string {
+	if len(e.Detail) > 0 {
+		return fmt.Sprintf("%s (%d %s)", e.Detail, e.Status, e.Message)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Message)
+}
+
+func dialREST(c Config, timeout time.Duration) (*restTransport, error) {
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &restTransport{
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+				Proxy:           http.ProxyFromEnvironment,
+			},
+		},
+		url:      "https://" + net.JoinHostPort(c.Address, strconv.Itoa(c.Port)) + "/rest",
+		username: c.Username,
+		password: c.Password,
+	}
+
+	// verify the connection and the credentials
+	if _, err := t.run(timeout, "/system/identity/print"); err != nil {
+		t.close()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *restTransport) run(timeout time.Duration, sentence ...string) ([]map[string]string, error) {
+
+	if len(sentence) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	body := make(map[string]interface{})
+	query := []string{}
+
+	for _, word := range sentence[1:] {
+		switch {
+		case strings.HasPrefix(word, "="):
+			kv := strings.SplitN(word[1:], "=", 2)
+			if len(kv) == 2 {
+				body[kv[0]] = kv[1]
+			} else {
+				body[kv[0]] = ""
+			}
+		case strings.HasPrefix(word, "?"):
+			query = append(query, word[1:])
+		}
+	}
+
+	if len(query) > 0 {
+		body[".query"] = query
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url+sentence[0], bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(t.username, t.password)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		rErr := &restError{}
+		if err := json.NewDecoder(resp.Body).Decode(rErr); err != nil || rErr.Status == 0 {
+			rErr.Status = resp.StatusCode
+			rErr.Message = http.StatusText(resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("%w: %s", ErrAuth, rErr)
+		}
+		return nil, &TrapError{Command: sentence[0], Message: rErr.String()}
+	}
+
+	var data interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	return restItems(data), nil
+}
+
+// restItems converts the JSON reply of the router into a list of items.
+// Commands like print return a list of objects, while others return a
+// single object or nothing at all.
+func restItems(data interface{}) []map[string]string {
+
+	items := []map[string]string{}
+
+	switch d := data.(type) {
+	case []interface{}:
+		for _, e := range d {
+			if obj, ok := e.(map[string]interface{}); ok {
+				items = append(items, restItem(obj))
+			}
+		}
+	case map[string]interface{}:
+		if len(d) > 0 {
+			items = append(items, restItem(d))
+		}
+	}
+
+	return items
+}
+
+func restItem(obj map[string]interface{}) map[string]string {
+	item := make(map[string]string, len(obj))
+	for k, v := range obj {
+		item[k] = fmt.Sprint(v)
+	}
+	return item
+}
+
+func (t *restTransport) close() {
+	t.client.CloseIdleConnections()
+}
@@ -0,0 +1,83 @@
+package routerostest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// reader reads the sentences of the RouterOS API protocol. In contrast to
+// proto.Reader, which reads the replies of a device, it accepts the words
+// of commands (e.g. queries).
+type reader struct {
+	*bufio.Reader
+}
+
+func newReader(r io.Reader) *reader {
+	return &reader{bufio.NewReader(r)}
+}
+
+// readSentence reads the words of a sentence until the terminating empty
+// word.
+func (r *reader) readSentence() ([]string, error) {
+	words := []string{}
+	for {
+		w, err := r.readWord()
+		if err != nil {
+			return nil, err
+		}
+		if len(w) == 0 {
+			return words, nil
+		}
+		words = append(words, w)
+	}
+}
+
+func (r *reader) readWord() (string, error) {
+	l, err := r.readLength()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readLength decodes the length of a word. Depending on its value, the
+// length is encoded in 1 to 5 bytes; the number of bytes is indicated by
+// the leading bits of the first byte.
+func (r *reader) readLength() (int, error) {
+
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var n, extra int
+	switch {
+	case b&0x80 == 0x00:
+		return int(b), nil
+	case b&0xC0 == 0x80:
+		n, extra = int(b&0x3F), 1
+	case b&0xE0 == 0xC0:
+		n, extra = int(b&0x1F), 2
+	case b&0xF0 == 0xE0:
+		n, extra = int(b&0x0F), 3
+	case b == 0xF0:
+		n, extra = 0, 4
+	default:
+		return 0, fmt.Errorf("invalid length prefix 0x%02x", b)
+	}
+
+	for i := 0; i < extra; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<8 | int(b)
+	}
+
+	return n, nil
+}
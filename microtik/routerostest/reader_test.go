@@ -0,0 +1,92 @@
+package routerostest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/routeros.v2/proto"
+)
+
+func TestReadSentence(t *testing.T) {
+
+	// cover all encodings of the word length
+	words := []string{
+		"/ip/route/print",
+		"?comment=" + strings.Repeat("a", 0x7F),
+		"=comment=" + strings.Repeat("b", 0x80),
+		"=comment=" + strings.Repeat("c", 0x4000),
+		"=comment=" + strings.Repeat("d", 0x200000),
+		".tag=1",
+	}
+
+	buf := &bytes.Buffer{}
+	w := proto.NewWriter(buf)
+	w.BeginSentence()
+	for _, word := range words {
+		w.WriteWord(word)
+	}
+	if err := w.EndSentence(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := newReader(buf).readSentence()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(words) {
+		t.Fatalf("got %d words, expected %d", len(got), len(words))
+	}
+	for i := range words {
+		if got[i] != words[i] {
+			t.Errorf("word %d: got %d bytes, expected %d", i, len(got[i]), len(words[i]))
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+
+	cmd := parseCommand([]string{"/ip/route/set", "=.id=*1", "=disabled=true", "=comment", "?gateway=lte1", ".tag=r3"})
+
+	if cmd.Path != "/ip/route/set" {
+		t.Errorf("path: got %q", cmd.Path)
+	}
+	if cmd.Args[".id"] != "*1" || cmd.Args["disabled"] != "true" {
+		t.Errorf("args: got %v", cmd.Args)
+	}
+	if v, ok := cmd.Args["comment"]; !ok || v != "" {
+		t.Errorf("empty arg: got %q, %v", v, ok)
+	}
+	if len(cmd.Queries) != 1 || cmd.Queries[0] != "gateway=lte1" {
+		t.Errorf("queries: got %v", cmd.Queries)
+	}
+	if cmd.Tag != "r3" {
+		t.Errorf("tag: got %q", cmd.Tag)
+	}
+}
+
+func TestMatches(t *testing.T) {
+
+	item := map[string]string{".id": "*1", "gateway": "lte1", "comment": ""}
+
+	tests := []struct {
+		queries []string
+		match   bool
+	}{
+		{nil, true},
+		{[]string{".id=*1"}, true},
+		{[]string{".id=*1", "gateway=lte1"}, true},
+		{[]string{".id=*1", "gateway=pppoe-out1"}, false},
+		{[]string{"comment"}, true},
+		{[]string{"distance"}, false},
+		{[]string{"-distance"}, true},
+		{[]string{"-gateway"}, false},
+	}
+
+	for _, tc := range tests {
+		if got := matches(item, tc.queries); got != tc.match {
+			t.Errorf("%v: got %v, expected %v", tc.queries, got, tc.match)
+		}
+	}
+}
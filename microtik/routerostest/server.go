@@ -0,0 +1,568 @@
+// Package routerostest provides an in-process RouterOS API server for
+// testing code which communicates with a microtik router, similar to
+// net/http/httptest. The server speaks the binary API protocol (including
+// the login) and simulates the routes of a router (/ip/route). Further
+// commands can be added with Handle. Errors, dropped connections and
+// latency can be injected per command.
+package routerostest
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/routeros.v2/proto"
+)
+
+// Command is a command (sentence) received by the server.
+type Command struct {
+	// Path of the command, e.g. /ip/route/print
+	Path string
+	// Args contains the attribute words (=key=value)
+	Args map[string]string
+	// Queries contains the query words (e.g. ?.id=*1) without the
+	// leading question mark
+	Queries []string
+	Tag     string
+}
+
+// HandlerFunc answers a command with the items of the reply (!re). If an
+// error is returned, the command fails with a !trap containing the error
+// message.
+type HandlerFunc func(cmd Command) ([]map[string]string, error)
+
+// Server is a fake RouterOS device which accepts API connections on a
+// random port of the loopback interface. All methods are safe for
+// concurrent use.
+type Server struct {
+	sync.Mutex
+	listener    net.Listener
+	username    string
+	password    string
+	legacyLogin bool
+	routes      []map[string]string
+	nextID      int
+	handlers    map[string]HandlerFunc
+	faults      map[string]string
+	hangups     map[string]bool
+	delays      map[string]time.Duration
+	commands    []Command
+	logins      int
+	resets      []string
+	conns       map[net.Conn]bool
+	wg          sync.WaitGroup
+}
+
+// Option is a function argument type for the Server constructor
+type Option func(s *Server)
+
+// Credentials is a functional option which sets the username and the
+// password accepted by the server (default: admin / no password).
+func Credentials(username, password string) Option {
+	return func(s *Server) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// LegacyLogin is a functional option which makes the server use the
+// challenge-response login of RouterOS versions before 6.43.
+func LegacyLogin() Option {
+	return func(s *Server) {
+		s.legacyLogin = true
+	}
+}
+
+// Route is a functional option which adds a route to the server (see
+// AddRoute).
+func Route(attributes map[string]string) Option {
+	return func(s *Server) {
+		s.addRoute(attributes)
+	}
+}
+
+// NewServer starts and returns a new Server. The caller must call Close
+// when finished, in order to shut it down. NewServer panics if it can't
+// listen on the loopback interface.
+func NewServer(opts ...Option) *Server {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("routerostest: failed to listen on a port: %v", err))
+	}
+
+	s := &Server{
+		listener: l,
+		username: "admin",
+		handlers: make(map[string]HandlerFunc),
+		faults:   make(map[string]string),
+		hangups:  make(map[string]bool),
+		delays:   make(map[string]time.Duration),
+		conns:    make(map[net.Conn]bool),
+	}
+
+	s.handlers["/system/identity/print"] = func(Command) ([]map[string]string, error) {
+		return []map[string]string{{"name": "MikroTik"}}, nil
+	}
+	s.handlers["/ip/route/print"] = s.printRoutes
+	s.handlers["/ip/route/set"] = s.setRoute
+	s.handlers["/ip/route/unset"] = s.unsetRoute
+	s.handlers["/system/routerboard/usb/power-reset"] = s.powerReset
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s
+}
+
+// Host returns the ip address on which the server listens.
+func (s *Server) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port on which the server listens.
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Close shuts down the server and closes all connections.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.CloseConnections()
+	s.wg.Wait()
+}
+
+// CloseConnections closes all established connections, e.g. in order to
+// simulate a reboot of the router. New connections are still accepted.
+func (s *Server) CloseConnections() {
+	s.Lock()
+	defer s.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// Handle registers the handler for a command path (e.g.
+// /ip/dhcp-client/print), replacing the built-in handler if there is one.
+func (s *Server) Handle(path string, h HandlerFunc) {
+	s.Lock()
+	defer s.Unlock()
+	s.handlers[path] = h
+}
+
+// Fail makes all subsequent commands with the given path fail with a
+// !trap containing the message, until ClearFaults is called. An empty
+// path matches all commands except the login (see Credentials).
+func (s *Server) Fail(path, message string) {
+	s.Lock()
+	defer s.Unlock()
+	s.faults[path] = message
+}
+
+// Hangup makes the server close the connection without a reply when it
+// receives a command with the given path, until ClearFaults is called. An
+// empty path matches all commands, including the login.
+func (s *Server) Hangup(path string) {
+	s.Lock()
+	defer s.Unlock()
+	s.hangups[path] = true
+}
+
+// Delay delays the replies to the commands with the given path, until
+// ClearFaults is called. An empty path matches all commands, including
+// the login.
+func (s *Server) Delay(path string, d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.delays[path] = d
+}
+
+// ClearFaults removes all errors, hangups and delays which have been
+// injected with Fail, Hangup and Delay.
+func (s *Server) ClearFaults() {
+	s.Lock()
+	defer s.Unlock()
+	s.faults = make(map[string]string)
+	s.hangups = make(map[string]bool)
+	s.delays = make(map[string]time.Duration)
+}
+
+// Commands returns all commands which have been received after a
+// successful login, in the order of their arrival.
+func (s *Server) Commands() []Command {
+	s.Lock()
+	defer s.Unlock()
+	return append([]Command{}, s.commands...)
+}
+
+// Logins returns the number of successful logins.
+func (s *Server) Logins() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.logins
+}
+
+// PowerResets returns the durations of all USB power resets
+// (/system/routerboard/usb/power-reset) which have been executed.
+func (s *Server) PowerResets() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string{}, s.resets...)
+}
+
+// AddRoute adds a route with the given attributes (e.g. comment,
+// dst-address, gateway) and returns its id. Unless set, the route gets a
+// new id (.id) and is enabled and active. A route becomes inactive when it
+// is disabled and active again when it is enabled.
+func (s *Server) AddRoute(attributes map[string]string) string {
+	s.Lock()
+	defer s.Unlock()
+	return s.addRoute(attributes)
+}
+
+func (s *Server) addRoute(attributes map[string]string) string {
+
+	r := map[string]string{
+		"disabled": "false",
+		"active":   "true",
+	}
+	for k, v := range attributes {
+		r[k] = v
+	}
+
+	if len(r[".id"]) == 0 {
+		r[".id"] = fmt.Sprintf("*%X", s.nextID+1)
+	}
+	s.nextID++
+
+	s.routes = append(s.routes, r)
+	return r[".id"]
+}
+
+// RouteAttributes returns a copy of the attributes of the route with the
+// given id, or nil if it doesn't exist.
+func (s *Server) RouteAttributes(id string) map[string]string {
+	s.Lock()
+	defer s.Unlock()
+
+	r := s.findRoute(id)
+	if r == nil {
+		return nil
+	}
+	return copyItem(r)
+}
+
+func (s *Server) findRoute(id string) map[string]string {
+	for _, r := range s.routes {
+		if r[".id"] == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// printRoutes implements /ip/route/print. Only simple queries (?key=value,
+// ?key, ?-key) are supported; they are combined with a logical AND.
+func (s *Server) printRoutes(cmd Command) ([]map[string]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	reply := []map[string]string{}
+	for _, r := range s.routes {
+		if matches(r, cmd.Queries) {
+			reply = append(reply, copyItem(r))
+		}
+	}
+	return reply, nil
+}
+
+func (s *Server) setRoute(cmd Command) ([]map[string]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	r := s.findRoute(cmd.Args[".id"])
+	if r == nil {
+		return nil, errors.New("no such item")
+	}
+
+	for k, v := range cmd.Args {
+		if k == ".id" {
+			continue
+		}
+		r[k] = v
+		if k == "disabled" {
+			r["active"] = strconv.FormatBool(v != "true")
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *Server) unsetRoute(cmd Command) ([]map[string]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	r := s.findRoute(cmd.Args[".id"])
+	if r == nil {
+		return nil, errors.New("no such item")
+	}
+
+	delete(r, cmd.Args["value-name"])
+	return nil, nil
+}
+
+func (s *Server) powerReset(cmd Command) ([]map[string]string, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.resets = append(s.resets, cmd.Args["duration"])
+	return nil, nil
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.Lock()
+		s.conns[conn] = true
+		s.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+			s.Lock()
+			delete(s.conns, conn)
+			s.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// handleConn executes the commands received on a connection until it is
+// closed. Like RouterOS, the commands are executed sequentially.
+func (s *Server) handleConn(conn net.Conn) {
+
+	r := newReader(conn)
+	w := proto.NewWriter(conn)
+	loggedIn := false
+	challenge := ""
+
+	for {
+		words, err := r.readSentence()
+		if err != nil {
+			return
+		}
+		if len(words) == 0 {
+			continue
+		}
+
+		cmd := parseCommand(words)
+
+		s.Lock()
+		if loggedIn {
+			s.commands = append(s.commands, cmd)
+		}
+		delay := s.delays[cmd.Path] + s.delays[""]
+		hangup := s.hangups[cmd.Path] || s.hangups[""]
+		fault, failed := s.faults[cmd.Path]
+		if !failed {
+			fault, failed = s.faults[""]
+		}
+		s.Unlock()
+
+		time.Sleep(delay)
+
+		if hangup {
+			return
+		}
+
+		if cmd.Path == "/login" {
+			reply := map[string]string{}
+			err := s.login(cmd, &challenge)
+			if err == nil && len(challenge) > 0 {
+				reply["ret"] = challenge
+			}
+			if err == nil && len(challenge) == 0 {
+				loggedIn = true
+			}
+			if writeReply(w, cmd.Tag, nil, reply, err) != nil {
+				return
+			}
+			continue
+		}
+
+		if !loggedIn {
+			writeReply(w, cmd.Tag, nil, nil, errors.New("not logged in"))
+			return
+		}
+
+		s.Lock()
+		h, ok := s.handlers[cmd.Path]
+		s.Unlock()
+
+		var items []map[string]string
+		switch {
+		case failed:
+			err = errors.New(fault)
+		case !ok:
+			err = errors.New("no such command prefix")
+		default:
+			items, err = h(cmd)
+		}
+
+		if writeReply(w, cmd.Tag, items, nil, err) != nil {
+			return
+		}
+	}
+}
+
+// login verifies the credentials of a /login command. With the legacy
+// login, the first /login command returns a challenge which has to be
+// answered by a second /login command.
+func (s *Server) login(cmd Command, challenge *string) error {
+
+	s.Lock()
+	defer s.Unlock()
+
+	errInvalid := errors.New("invalid user name or password (6)")
+
+	if !s.legacyLogin {
+		if cmd.Args["name"] != s.username || cmd.Args["password"] != s.password {
+			return errInvalid
+		}
+		s.logins++
+		return nil
+	}
+
+	if len(*challenge) == 0 {
+		*challenge = fmt.Sprintf("%032x", time.Now().UnixNano())
+		return nil
+	}
+
+	c, _ := hex.DecodeString(*challenge)
+	*challenge = ""
+
+	h := md5.New()
+	h.Write([]byte{0})
+	io.WriteString(h, s.password)
+	h.Write(c)
+
+	if cmd.Args["name"] != s.username || cmd.Args["response"] != fmt.Sprintf("00%x", h.Sum(nil)) {
+		return errInvalid
+	}
+
+	s.logins++
+	return nil
+}
+
+func parseCommand(words []string) Command {
+
+	cmd := Command{
+		Path: words[0],
+		Args: make(map[string]string),
+	}
+
+	for _, word := range words[1:] {
+		switch {
+		case strings.HasPrefix(word, ".tag="):
+			cmd.Tag = strings.TrimPrefix(word, ".tag=")
+		case strings.HasPrefix(word, "="):
+			kv := strings.SplitN(word[1:], "=", 2)
+			if len(kv) == 1 {
+				kv = append(kv, "")
+			}
+			cmd.Args[kv[0]] = kv[1]
+		case strings.HasPrefix(word, "?"):
+			cmd.Queries = append(cmd.Queries, word[1:])
+		}
+	}
+
+	return cmd
+}
+
+// matches returns true if the item satisfies all queries.
+func matches(item map[string]string, queries []string) bool {
+	for _, q := range queries {
+		switch {
+		case strings.HasPrefix(q, "-"):
+			if _, ok := item[q[1:]]; ok {
+				return false
+			}
+		case strings.Contains(q, "="):
+			kv := strings.SplitN(q, "=", 2)
+			if v, ok := item[kv[0]]; !ok || v != kv[1] {
+				return false
+			}
+		default:
+			if _, ok := item[q]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func copyItem(item map[string]string) map[string]string {
+	c := make(map[string]string, len(item))
+	for k, v := range item {
+		c[k] = v
+	}
+	return c
+}
+
+// writeReply sends the items (!re), followed by !done with the optional
+// attributes. If err is not nil, a !trap with its message is sent instead
+// of the items, followed by !done (like RouterOS does).
+func writeReply(w proto.Writer, tag string, items []map[string]string, done map[string]string, err error) error {
+
+	if err != nil {
+		if e := writeSentence(w, "!trap", tag, map[string]string{"message": err.Error()}); e != nil {
+			return e
+		}
+		return writeSentence(w, "!done", tag, nil)
+	}
+
+	for _, item := range items {
+		if e := writeSentence(w, "!re", tag, item); e != nil {
+			return e
+		}
+	}
+
+	return writeSentence(w, "!done", tag, done)
+}
+
+func writeSentence(w proto.Writer, word, tag string, attributes map[string]string) error {
+
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w.BeginSentence()
+	w.WriteWord(word)
+	if len(tag) > 0 {
+		w.WriteWord(".tag=" + tag)
+	}
+	for _, k := range keys {
+		w.WriteWord("=" + k + "=" + attributes[k])
+	}
+	return w.EndSentence()
+}
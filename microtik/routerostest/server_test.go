@@ -0,0 +1,172 @@
+package routerostest
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"gopkg.in/routeros.v2"
+)
+
+func dial(t *testing.T, s *Server, username, password string) (*routeros.Client, error) {
+	t.Helper()
+	return routeros.DialTimeout(net.JoinHostPort(s.Host(), strconv.Itoa(s.Port())), username, password, time.Second)
+}
+
+func TestLogin(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		username string
+		password string
+		ok       bool
+	}{
+		{"default", nil, "admin", "", true},
+		{"credentials", []Option{Credentials("infractl", "secret")}, "infractl", "secret", true},
+		{"wrong password", []Option{Credentials("infractl", "secret")}, "infractl", "wrong", false},
+		{"legacy", []Option{Credentials("infractl", "secret"), LegacyLogin()}, "infractl", "secret", true},
+		{"legacy wrong password", []Option{Credentials("infractl", "secret"), LegacyLogin()}, "infractl", "wrong", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(tc.opts...)
+			defer s.Close()
+
+			c, err := dial(t, s, tc.username, tc.password)
+			if tc.ok != (err == nil) {
+				t.Fatalf("login: %v", err)
+			}
+			if err != nil {
+				if s.Logins() != 0 {
+					t.Errorf("got %d logins", s.Logins())
+				}
+				return
+			}
+			defer c.Close()
+
+			if s.Logins() != 1 {
+				t.Errorf("got %d logins, expected 1", s.Logins())
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+
+	s := NewServer(
+		Route(map[string]string{"comment": "adsl", "gateway": "pppoe-out1"}),
+		Route(map[string]string{"comment": "4g", "gateway": "lte1", "disabled": "true", "active": "false"}),
+	)
+	defer s.Close()
+
+	c, err := dial(t, s, "admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	r, err := c.Run("/ip/route/print")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Re) != 2 || r.Re[0].Map[".id"] != "*1" || r.Re[1].Map[".id"] != "*2" {
+		t.Fatalf("unexpected reply %v", r)
+	}
+
+	r, err = c.Run("/ip/route/print", "?gateway=lte1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Re) != 1 || r.Re[0].Map["comment"] != "4g" {
+		t.Fatalf("unexpected reply %v", r)
+	}
+
+	if _, err := c.Run("/ip/route/set", "=.id=*2", "=disabled=false"); err != nil {
+		t.Fatal(err)
+	}
+	if a := s.RouteAttributes("*2"); a["disabled"] != "false" || a["active"] != "true" {
+		t.Errorf("route not enabled: %v", a)
+	}
+
+	if _, err := c.Run("/ip/route/unset", "=.id=*2", "=value-name=comment"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.RouteAttributes("*2")["comment"]; ok {
+		t.Errorf("comment not removed")
+	}
+
+	if _, err := c.Run("/ip/route/set", "=.id=*9", "=disabled=false"); err == nil {
+		t.Errorf("setting an unknown route succeeded")
+	}
+}
+
+func TestFaults(t *testing.T) {
+
+	s := NewServer()
+	defer s.Close()
+
+	c, err := dial(t, s, "admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Async()
+
+	s.Fail("/system/routerboard/usb/power-reset", "failure: no usb power reset support")
+
+	_, err = c.Run("/system/routerboard/usb/power-reset", "=duration=5s")
+	if devErr, ok := err.(*routeros.DeviceError); !ok || devErr.Sentence.Map["message"] != "failure: no usb power reset support" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the trailing !done of the trap must not be taken as the next reply
+	r, err := c.Run("/system/identity/print")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Re) != 1 || r.Re[0].Map["name"] != "MikroTik" {
+		t.Fatalf("unexpected reply %v", r)
+	}
+
+	if _, err := c.Run("/interface/print"); err == nil {
+		t.Errorf("unknown command succeeded")
+	}
+
+	s.ClearFaults()
+	if _, err := c.Run("/system/routerboard/usb/power-reset", "=duration=5s"); err != nil {
+		t.Fatal(err)
+	}
+	if resets := s.PowerResets(); len(resets) != 1 || resets[0] != "5s" {
+		t.Errorf("got power resets %v", resets)
+	}
+
+	s.Handle("/interface/print", func(cmd Command) ([]map[string]string, error) {
+		return []map[string]string{{"name": "lte1"}}, nil
+	})
+	if r, err := c.Run("/interface/print"); err != nil || len(r.Re) != 1 {
+		t.Errorf("custom handler: %v, %v", r, err)
+	}
+
+	s.Delay("/system/identity/print", time.Millisecond*100)
+	start := time.Now()
+	if _, err := c.Run("/system/identity/print"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < time.Millisecond*100 {
+		t.Errorf("reply not delayed (%v)", d)
+	}
+
+	s.Hangup("/ip/route/print")
+	if _, err := c.Run("/ip/route/print"); err == nil {
+		t.Errorf("command succeeded after hangup")
+	}
+
+	cmds := s.Commands()
+	if len(cmds) != 7 || cmds[0].Path != "/system/routerboard/usb/power-reset" || cmds[0].Args["duration"] != "5s" {
+		t.Errorf("unexpected commands %v", cmds)
+	}
+}
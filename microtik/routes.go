@@ -0,0 +1,365 @@
+package microtik
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Route contains the attributes of a route (ip/route) on the router.
+type Route struct {
+	ID          string `json:"id"`
+	DstAddress  string `json:"dst_address"`
+	Gateway     string `json:"gateway"`
+	Distance    int    `json:"distance"`
+	RoutingMark string `json:"routing_mark"`
+	Active      bool   `json:"active"`
+	Disabled    bool   `json:"disabled"`
+	Dynamic     bool   `json:"dynamic"`
+	Comment     string `json:"comment"`
+}
+
+// Routes returns all routes (ip/route) configured on the router, including
+// the dynamic ones. The routes don't have to be registered.
+func (m *Microtik) Routes() ([]Route, error) {
+
+	reply, err := m.run("/ip/route/print")
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]Route, 0, len(reply))
+	for _, r := range reply {
+		routes = append(routes, parseRoute(r))
+	}
+
+	return routes, nil
+}
+
+// parseRoute converts the attributes of a route as returned by the router
+// into a Route.
+func parseRoute(r map[string]string) Route {
+
+	route := Route{
+		ID:          r[".id"],
+		DstAddress:  r["dst-address"],
+		Gateway:     r["gateway"],
+		RoutingMark: r["routing-mark"],
+		Active:      r["active"] == "true",
+		Disabled:    r["disabled"] == "true",
+		Dynamic:     r["dynamic"] == "true",
+		Comment:     r["comment"],
+	}
+
+	// RouterOS v7 replaced routing marks with routing tables
+	if len(route.RoutingMark) == 0 {
+		route.RoutingMark = r["routing-table"]
+	}
+
+	if d, err := strconv.Atoi(r["distance"]); err == nil {
+		route.Distance = d
+	}
+
+	return route
+}
+
+// RouteSelector identifies a route on the router. A route matches if it
+// matches all the non-empty fields of the selector.
+type RouteSelector struct {
+	// Comment must match the route's comment exactly
+	Comment string
+	// CommentRegex is matched against the route's comment
+	CommentRegex *regexp.Regexp
+	DstAddress   string
+	Gateway      string
+	RoutingMark  string
+	// ID is the RouterOS internal id (e.g. *1). It does not survive
+	// deleting and re-creating a route.
+	ID string
+}
+
+// IsEmpty returns true if no field of the selector is set. An empty
+// selector matches all routes.
+func (s RouteSelector) IsEmpty() bool {
+	return len(s.Comment) == 0 && s.CommentRegex == nil && len(s.DstAddress) == 0 &&
+		len(s.Gateway) == 0 && len(s.RoutingMark) == 0 && len(s.ID) == 0
+}
+
+// Matches returns true if the route matches all non-empty fields of the
+// selector.
+func (s RouteSelector) Matches(r Route) bool {
+	if len(s.Comment) > 0 && s.Comment != r.Comment {
+		return false
+	}
+	if s.CommentRegex != nil && !s.CommentRegex.MatchString(r.Comment) {
+		return false
+	}
+	if len(s.DstAddress) > 0 && s.DstAddress != r.DstAddress {
+		return false
+	}
+	if len(s.Gateway) > 0 && s.Gateway != r.Gateway {
+		return false
+	}
+	if len(s.RoutingMark) > 0 && s.RoutingMark != r.RoutingMark {
+		return false
+	}
+	if len(s.ID) > 0 && s.ID != r.ID {
+		return false
+	}
+	return true
+}
+
+func (s RouteSelector) String() string {
+	fields := []string{}
+	if len(s.Comment) > 0 {
+		fields = append(fields, fmt.Sprintf("comment=%q", s.Comment))
+	}
+	if s.CommentRegex != nil {
+		fields = append(fields, fmt.Sprintf("comment~%q", s.CommentRegex))
+	}
+	if len(s.DstAddress) > 0 {
+		fields = append(fields, "dst-address="+s.DstAddress)
+	}
+	if len(s.Gateway) > 0 {
+		fields = append(fields, "gateway="+s.Gateway)
+	}
+	if len(s.RoutingMark) > 0 {
+		fields = append(fields, "routing-mark="+s.RoutingMark)
+	}
+	if len(s.ID) > 0 {
+		fields = append(fields, ".id="+s.ID)
+	}
+	return strings.Join(fields, " ")
+}
+
+// findRoute retrieves the attributes of a registered route from the router.
+func (m *Microtik) findRoute(name string) (map[string]string, error) {
+
+	sel, ok := m.routes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownRoute, name)
+	}
+
+	reply, err := m.run("/ip/route/print")
+	if err != nil {
+		return nil, err
+	}
+
+	route, err := getRoute(reply, sel)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrRouteNotFound, name, err)
+	}
+
+	return route, nil
+}
+
+// getRoute retrieves the parameters of the route matching the selector from
+// the reply of /ip/route/print. Exactly one route must match.
+func getRoute(reply []map[string]string, sel RouteSelector) (map[string]string, error) {
+
+	if reply == nil {
+		return nil, fmt.Errorf("router response nil")
+	}
+
+	if len(reply) == 0 {
+		return nil, fmt.Errorf("router response empty")
+	}
+
+	var matches []map[string]string
+
+	for _, r := range reply {
+		if sel.Matches(parseRoute(r)) {
+			matches = append(matches, r)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no route matches %s", sel)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := []string{}
+		for _, r := range matches {
+			ids = append(ids, r[".id"])
+		}
+		return nil, fmt.Errorf("ambiguous selector %s, %d routes match (%s)",
+			sel, len(matches), strings.Join(ids, ", "))
+	}
+}
+
+// RouteOp is a typed modification of a single route attribute. RouteOps
+// are created with Enable, Disable, SetDistance, SetGateway,
+// SetCheckGateway and SetComment.
+type RouteOp struct {
+	Attribute string
+	Value     string
+	// unset removes the attribute instead of setting it (used for
+	// restoring attributes which were not set before)
+	unset bool
+	err   error
+}
+
+func (op RouteOp) String() string {
+	return fmt.Sprintf("%s=%s", op.Attribute, op.Value)
+}
+
+// Enable enables a route.
+func Enable() RouteOp {
+	return RouteOp{Attribute: "disabled", Value: "false"}
+}
+
+// Disable disables a route.
+func Disable() RouteOp {
+	return RouteOp{Attribute: "disabled", Value: "true"}
+}
+
+// SetDistance sets the distance (1-255) of a route.
+func SetDistance(distance int) RouteOp {
+	op := RouteOp{Attribute: "distance", Value: strconv.Itoa(distance)}
+	if distance < 1 || distance > 255 {
+		op.err = fmt.Errorf("invalid distance %d (1-255)", distance)
+	}
+	return op
+}
+
+// SetGateway sets the gateway (ip address or interface) of a route.
+func SetGateway(gateway string) RouteOp {
+	op := RouteOp{Attribute: "gateway", Value: gateway}
+	if len(strings.TrimSpace(gateway)) == 0 {
+		op.err = fmt.Errorf("empty gateway")
+	}
+	return op
+}
+
+// SetCheckGateway sets the method (arp, ping, bfd or none) used to check
+// the reachability of the gateway of a route.
+func SetCheckGateway(method string) RouteOp {
+	op := RouteOp{Attribute: "check-gateway", Value: method}
+	switch method {
+	case "arp", "ping", "bfd", "none":
+	default:
+		op.err = fmt.Errorf("invalid check-gateway method %q (arp, ping, bfd, none)", method)
+	}
+	return op
+}
+
+// SetComment sets the comment of a route. Note that routes registered by
+// their comment won't be found anymore after changing it.
+func SetComment(comment string) RouteOp {
+	return RouteOp{Attribute: "comment", Value: comment}
+}
+
+// UpdateRoute applies one or more modifications to a registered route in a
+// single call. Only the attributes allowed through the RouteAttributes option
+// can be modified. Afterwards the route is read back from the router in
+// order to verify that all modifications have been applied. The route
+// can't be modified while a transaction is running.
+func (m *Microtik) UpdateRoute(name string, ops ...RouteOp) error {
+
+	name = strings.ToLower(name)
+
+	// a transaction which starts in the meantime waits until the route
+	// has been modified
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	if m.txRunning {
+		return fmt.Errorf("unable to modify route %s: %w", name, ErrTransactionRunning)
+	}
+
+	if err := m.checkOps(name, ops); err != nil {
+		return err
+	}
+
+	route, err := m.findRoute(name)
+	if err != nil {
+		return err
+	}
+
+	id, ok := route[".id"]
+	if !ok {
+		return fmt.Errorf("unable to determine the id of route %s", name)
+	}
+
+	return m.applyOps(name, id, ops)
+}
+
+// checkOps verifies that the modifications of a route are valid and
+// allowed.
+func (m *Microtik) checkOps(name string, ops []RouteOp) error {
+
+	if len(ops) == 0 {
+		return fmt.Errorf("%w: no modifications for route %s provided", ErrInvalidRouteOp, name)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, op := range ops {
+		if op.err != nil {
+			return fmt.Errorf("%w: route %s: %v", ErrInvalidRouteOp, name, op.err)
+		}
+		if !m.routeAttributes[op.Attribute] {
+			return fmt.Errorf("%w: route %s: modifying attribute %s is not allowed", ErrInvalidRouteOp, name, op.Attribute)
+		}
+		if seen[op.Attribute] {
+			return fmt.Errorf("%w: route %s: attribute %s modified more than once", ErrInvalidRouteOp, name, op.Attribute)
+		}
+		seen[op.Attribute] = true
+	}
+
+	return nil
+}
+
+// applyOps applies the modifications to the route with the given id and
+// reads the route back in order to verify them.
+func (m *Microtik) applyOps(name, id string, ops []RouteOp) error {
+
+	set := []string{"/ip/route/set", "=.id=" + id}
+	for _, op := range ops {
+		if !op.unset {
+			set = append(set, "="+op.Attribute+"="+op.Value)
+		}
+	}
+
+	if len(set) > 2 {
+		if _, err := m.run(set...); err != nil {
+			return err
+		}
+	}
+
+	for _, op := range ops {
+		if op.unset {
+			_, err := m.run("/ip/route/unset", "=.id="+id, "=value-name="+op.Attribute)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// read back the route by its id, since the modifications might
+	// affect the selector (e.g. the comment)
+	reply, err := m.run("/ip/route/print", "?.id="+id)
+	if err != nil {
+		return fmt.Errorf("unable to verify the modification of route %s: %w", name, err)
+	}
+
+	if len(reply) != 1 {
+		return fmt.Errorf("unable to verify the modification of route %s: route %s not found", name, id)
+	}
+
+	for _, op := range ops {
+		v := reply[0][op.Attribute]
+		// unset attributes are omitted by the router
+		if op.Attribute == "check-gateway" && op.Value == "none" && len(v) == 0 {
+			continue
+		}
+		if v != op.Value {
+			return fmt.Errorf("route %s: %s is %q after the modification, expected %q",
+				name, op.Attribute, v, op.Value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,331 @@
+package microtik
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dh1tw/infractl/microtik/routerostest"
+)
+
+func TestGetRoute(t *testing.T) {
+
+	reply := []map[string]string{
+		{".id": "*1", "dst-address": "0.0.0.0/0", "gateway": "pppoe-out1", "comment": "upstream route to adsl"},
+		{".id": "*2", "dst-address": "0.0.0.0/0", "gateway": "lte1", "comment": "Backup route via 4G"},
+		{".id": "*3", "dst-address": "10.0.0.0/8", "gateway": "lte1", "comment": "Backup route via 4G"},
+		{".id": "*4", "dst-address": "0.0.0.0/0", "gateway": "pppoe-out1", "routing-table": "remote"},
+	}
+
+	tests := []struct {
+		name  string
+		reply []map[string]string
+		sel   RouteSelector
+		id    string
+		err   string
+	}{
+		{"nil reply", nil, RouteSelector{Comment: "upstream route to adsl"}, "", "router response nil"},
+		{"empty reply", []map[string]string{}, RouteSelector{Comment: "upstream route to adsl"}, "", "router response empty"},
+		{"comment", reply, RouteSelector{Comment: "upstream route to adsl"}, "*1", ""},
+		{"comment is case sensitive", reply, RouteSelector{Comment: "Upstream route to adsl"}, "", "no route matches"},
+		{"comment regex", reply, RouteSelector{CommentRegex: regexp.MustCompile("adsl$")}, "*1", ""},
+		{"ambiguous", reply, RouteSelector{CommentRegex: regexp.MustCompile("(?i)backup route via 4g")}, "", "2 routes match (*2, *3)"},
+		{"ambiguity resolved", reply, RouteSelector{CommentRegex: regexp.MustCompile("(?i)backup route via 4g"), DstAddress: "0.0.0.0/0"}, "*2", ""},
+		{"gateway", reply, RouteSelector{Gateway: "lte1", DstAddress: "10.0.0.0/8"}, "*3", ""},
+		{"routing table", reply, RouteSelector{RoutingMark: "remote"}, "*4", ""},
+		{"id", reply, RouteSelector{ID: "*2"}, "*2", ""},
+		{"no match", reply, RouteSelector{Gateway: "ether1"}, "", "no route matches gateway=ether1"},
+		{"empty selector", reply, RouteSelector{}, "", "4 routes match"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			route, err := getRoute(tc.reply, tc.sel)
+			if len(tc.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, expected %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if route[".id"] != tc.id {
+				t.Errorf("got route %s, expected %s", route[".id"], tc.id)
+			}
+		})
+	}
+}
+
+// newTestRoutes returns a fake router with an enabled adsl route and a
+// disabled 4g route.
+func newTestRoutes() *routerostest.Server {
+	return routerostest.NewServer(
+		routerostest.Route(map[string]string{
+			"dst-address": "0.0.0.0/0",
+			"gateway":     "pppoe-out1",
+			"distance":    "1",
+			"comment":     "upstream route to adsl",
+		}),
+		routerostest.Route(map[string]string{
+			"dst-address": "0.0.0.0/0",
+			"gateway":     "lte1",
+			"distance":    "2",
+			"comment":     "backup route via 4g",
+			"disabled":    "true",
+			"active":      "false",
+		}),
+	)
+}
+
+var testRoutes = []Option{
+	RouteID("adsl", "upstream route to adsl"),
+	RouteID("4G", "backup route via 4g"),
+	RouteID("missing", "route which doesn't exist"),
+}
+
+func TestRouteStatus(t *testing.T) {
+
+	srv := newTestRoutes()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, testRoutes...)
+
+	tests := []struct {
+		route    string
+		disabled bool
+		active   bool
+		err      string
+	}{
+		{"adsl", false, true, ""},
+		{"4g", true, false, ""},
+		{"4G", true, false, ""},
+		{"vdsl", false, false, "unknown route vdsl"},
+		{"missing", false, false, "unable to find route missing"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.route, func(t *testing.T) {
+			res, err := m.RouteStatus(tc.route)
+			if len(tc.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, expected %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res["disabled"] != tc.disabled || res["active"] != tc.active {
+				t.Errorf("got %v", res)
+			}
+		})
+	}
+}
+
+func TestRouteStatusIncomplete(t *testing.T) {
+
+	srv := routerostest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("/ip/route/print", func(routerostest.Command) ([]map[string]string, error) {
+		return []map[string]string{{".id": "*1", "comment": "upstream route to adsl", "disabled": "false"}}, nil
+	})
+
+	m := newTestMicrotik(t, srv, testRoutes...)
+
+	_, err := m.RouteStatus("adsl")
+	if err == nil || !strings.Contains(err.Error(), "parameter active") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestRouteStatusTrap(t *testing.T) {
+
+	srv := newTestRoutes()
+	defer srv.Close()
+
+	srv.Fail("/ip/route/print", "not enough permissions (9)")
+
+	m := newTestMicrotik(t, srv, testRoutes...)
+
+	_, err := m.RouteStatus("adsl")
+	if err == nil || !strings.Contains(err.Error(), "not enough permissions") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestUpdateRoute(t *testing.T) {
+
+	srv := newTestRoutes()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, testRoutes...)
+
+	if err := m.UpdateRoute("adsl", Disable()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateRoute("4g", Enable()); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := srv.RouteAttributes("*1"); r["disabled"] != "true" || r["active"] != "false" {
+		t.Errorf("adsl not disabled: %v", r)
+	}
+	if r := srv.RouteAttributes("*2"); r["disabled"] != "false" || r["active"] != "true" {
+		t.Errorf("4g not enabled: %v", r)
+	}
+
+	// only the registered route is modified and read back
+	var sets []routerostest.Command
+	for _, cmd := range srv.Commands() {
+		if cmd.Path == "/ip/route/set" {
+			sets = append(sets, cmd)
+		}
+	}
+	if len(sets) != 2 || sets[0].Args[".id"] != "*1" || sets[0].Args["disabled"] != "true" || len(sets[0].Args) != 2 {
+		t.Errorf("unexpected commands %v", sets)
+	}
+}
+
+func TestUpdateRouteAttributes(t *testing.T) {
+
+	srv := newTestRoutes()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, append(testRoutes, RouteAttributes("disabled", "distance"))...)
+
+	if err := m.UpdateRoute("4g", Enable(), SetDistance(1)); err != nil {
+		t.Fatal(err)
+	}
+	if r := srv.RouteAttributes("*2"); r["disabled"] != "false" || r["distance"] != "1" {
+		t.Errorf("4g not modified: %v", r)
+	}
+
+	tests := []struct {
+		name string
+		ops  []RouteOp
+		err  string
+	}{
+		{"no ops", nil, "no modifications"},
+		{"not allowed", []RouteOp{SetGateway("lte2")}, "modifying attribute gateway is not allowed"},
+		{"invalid", []RouteOp{SetDistance(0)}, "invalid distance 0"},
+		{"duplicate", []RouteOp{Enable(), Disable()}, "attribute disabled modified more than once"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			before := len(srv.Commands())
+			err := m.UpdateRoute("adsl", tc.ops...)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("got error %v, expected %q", err, tc.err)
+			}
+			// invalid modifications are rejected before contacting the router
+			if n := len(srv.Commands()) - before; n != 0 {
+				t.Errorf("%d commands sent", n)
+			}
+		})
+	}
+}
+
+func TestUpdateRouteErrors(t *testing.T) {
+
+	srv := newTestRoutes()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, testRoutes...)
+
+	if err := m.UpdateRoute("vdsl", Enable()); err == nil || !strings.Contains(err.Error(), "unknown route vdsl") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if err := m.UpdateRoute("missing", Enable()); err == nil || !strings.Contains(err.Error(), "unable to find route missing") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	srv.Fail("/ip/route/set", "failure: route is read-only")
+	if err := m.UpdateRoute("adsl", Disable()); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("unexpected error %v", err)
+	}
+	srv.ClearFaults()
+
+	// the router accepts the command, but the route remains unchanged
+	srv.Handle("/ip/route/set", func(routerostest.Command) ([]map[string]string, error) {
+		return nil, nil
+	})
+	if err := m.UpdateRoute("adsl", Disable()); err == nil || !strings.Contains(err.Error(), "after the modification") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// the route can't be read back
+	srv.Handle("/ip/route/print", func(cmd routerostest.Command) ([]map[string]string, error) {
+		for _, q := range cmd.Queries {
+			if strings.HasPrefix(q, ".id=") {
+				return nil, errors.New("no such item")
+			}
+		}
+		return []map[string]string{{".id": "*1", "comment": "upstream route to adsl", "disabled": "false"}}, nil
+	})
+	if err := m.UpdateRoute("adsl", Disable()); !errors.Is(err, ErrRouterTrap) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if srv.Logins() != 1 {
+		t.Errorf("got %d logins, expected 1", srv.Logins())
+	}
+}
+
+func TestUpdateRouteDuringTransaction(t *testing.T) {
+
+	srv := newTestRoutes()
+	defer srv.Close()
+
+	m := newTestMicrotik(t, srv, testRoutes...)
+
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	probe := func() error {
+		close(probing)
+		<-release
+		return nil
+	}
+
+	type result struct {
+		TransactionResult
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		res, err := m.Transaction(probe, []RouteChange{{Route: "4g", Ops: []RouteOp{Enable()}}})
+		done <- result{res, err}
+	}()
+
+	<-probing
+
+	if err := m.UpdateRoute("adsl", Disable()); !errors.Is(err, ErrTransactionRunning) {
+		t.Errorf("UpdateRoute: unexpected error %v", err)
+	}
+	if _, err := m.Transaction(probe, []RouteChange{{Route: "adsl", Ops: []RouteOp{Disable()}}}); !errors.Is(err, ErrTransactionRunning) {
+		t.Errorf("Transaction: unexpected error %v", err)
+	}
+	if err := m.CheckTransaction([]RouteChange{{Route: "adsl", Ops: []RouteOp{Disable()}}}); !errors.Is(err, ErrTransactionRunning) {
+		t.Errorf("CheckTransaction: unexpected error %v", err)
+	}
+
+	close(release)
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if !res.Verified || res.Duration <= 0 {
+		t.Errorf("unexpected result %+v", res.TransactionResult)
+	}
+
+	if err := m.UpdateRoute("adsl", Disable()); err != nil {
+		t.Errorf("unexpected error after the transaction: %v", err)
+	}
+	if r := srv.RouteAttributes("*1"); r["disabled"] != "true" {
+		t.Errorf("adsl not disabled: %v", r)
+	}
+}
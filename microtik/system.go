@@ -0,0 +1,177 @@
+package microtik
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SystemResource contains the system resources (/system/resource) of
+// the router. Memory and disk sizes are in bytes.
+type SystemResource struct {
+	Uptime        time.Duration `json:"uptime"`
+	Version       string        `json:"version"`
+	BoardName     string        `json:"board_name"`
+	Architecture  string        `json:"architecture"`
+	CPU           string        `json:"cpu"`
+	CPUCount      int           `json:"cpu_count"`
+	CPUFrequency  int           `json:"cpu_frequency"` // MHz
+	CPULoad       int           `json:"cpu_load"`      // percent
+	FreeMemory    uint64        `json:"free_memory"`
+	TotalMemory   uint64        `json:"total_memory"`
+	FreeHDDSpace  uint64        `json:"free_hdd_space"`
+	TotalHDDSpace uint64        `json:"total_hdd_space"`
+}
+
+// Health contains the readings of the sensors (/system/health) of the
+// router. Sensors which are not available on the routerboard are nil.
+type Health struct {
+	Voltage        *float64 `json:"voltage,omitempty"`         // V
+	Temperature    *float64 `json:"temperature,omitempty"`     // °C
+	CPUTemperature *float64 `json:"cpu_temperature,omitempty"` // °C
+	// Sensors contains all readings reported by the router
+	Sensors map[string]string `json:"sensors"`
+}
+
+// SystemResource returns the system resources (uptime, cpu load, memory...)
+// of the router.
+func (m *Microtik) SystemResource() (SystemResource, error) {
+
+	reply, err := m.run("/system/resource/print")
+	if err != nil {
+		return SystemResource{}, err
+	}
+
+	if len(reply) == 0 {
+		return SystemResource{}, fmt.Errorf("router response empty")
+	}
+
+	r := reply[0]
+
+	res := SystemResource{
+		Version:       r["version"],
+		BoardName:     r["board-name"],
+		Architecture:  r["architecture-name"],
+		CPU:           r["cpu"],
+		CPUCount:      atoi(r["cpu-count"]),
+		CPUFrequency:  atoi(r["cpu-frequency"]),
+		CPULoad:       atoi(r["cpu-load"]),
+		FreeMemory:    atou(r["free-memory"]),
+		TotalMemory:   atou(r["total-memory"]),
+		FreeHDDSpace:  atou(r["free-hdd-space"]),
+		TotalHDDSpace: atou(r["total-hdd-space"]),
+	}
+
+	if uptime, err := ParseDuration(r["uptime"]); err == nil {
+		res.Uptime = uptime
+	}
+
+	return res, nil
+}
+
+// Health returns the readings of the sensors (voltage, temperature...) of
+// the router.
+func (m *Microtik) Health() (Health, error) {
+
+	reply, err := m.run("/system/health/print")
+	if err != nil {
+		return Health{}, err
+	}
+
+	h := Health{
+		Sensors: make(map[string]string),
+	}
+
+	for _, r := range reply {
+		// RouterOS v7 returns one item per sensor, while RouterOS v6
+		// returns all sensors as attributes of a single item
+		if name, ok := r["name"]; ok {
+			h.Sensors[name] = r["value"]
+			continue
+		}
+		for k, v := range r {
+			if strings.HasPrefix(k, ".") {
+				continue
+			}
+			h.Sensors[k] = v
+		}
+	}
+
+	h.Voltage = sensor(h.Sensors, "voltage")
+	h.Temperature = sensor(h.Sensors, "temperature")
+	h.CPUTemperature = sensor(h.Sensors, "cpu-temperature")
+
+	return h, nil
+}
+
+func sensor(sensors map[string]string, name string) *float64 {
+	v, ok := sensors[name]
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+func atou(s string) uint64 {
+	u, _ := strconv.ParseUint(s, 10, 64)
+	return u
+}
+
+// ParseDuration parses a duration in the notation of RouterOS
+// (e.g. "2w1d03:04:05", "1w2d3h4m5s" or "500ms").
+func ParseDuration(s string) (time.Duration, error) {
+
+	if len(s) == 0 {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	var d time.Duration
+	rest := s
+
+	// weeks and days are not supported by time.ParseDuration
+	for _, unit := range []struct {
+		suffix string
+		d      time.Duration
+	}{{"w", time.Hour * 24 * 7}, {"d", time.Hour * 24}} {
+		i := strings.Index(rest, unit.suffix)
+		if i < 0 {
+			continue
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", s)
+		}
+		d += time.Duration(n) * unit.d
+		rest = rest[i+1:]
+	}
+
+	if len(rest) == 0 {
+		return d, nil
+	}
+
+	// hh:mm:ss
+	if strings.Contains(rest, ":") {
+		parts := strings.Split(rest, ":")
+		if len(parts) != 3 {
+			return 0, fmt.Errorf("invalid duration %s", s)
+		}
+		rest = parts[0] + "h" + parts[1] + "m" + parts[2] + "s"
+	}
+
+	r, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %s", s)
+	}
+
+	return d + r, nil
+}
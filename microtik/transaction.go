@@ -0,0 +1,286 @@
+package microtik
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dh1tw/infractl/connectivity"
+)
+
+// Probe checks the connectivity after routes have been modified within a
+// transaction. It returns an error if the connectivity is not given.
+type Probe func() error
+
+// PingProbe returns a Probe which pings a host and fails if the host is
+// unreachable or the packet loss exceeds maxLoss (in percent).
+func PingProbe(host string, timeout time.Duration, samples int, maxLoss float64) Probe {
+	return func() error {
+		res, err := connectivity.PingHost(host, timeout, samples)
+		if err != nil {
+			return err
+		}
+		if res.Loss > maxLoss {
+			return fmt.Errorf("packet loss to %s %.0f%% exceeds %.0f%%", host, res.Loss, maxLoss)
+		}
+		return nil
+	}
+}
+
+// RouteChange contains the modifications of a registered route.
+type RouteChange struct {
+	Route string
+	Ops   []RouteOp
+}
+
+// TransactionResult describes the outcome of a transaction.
+type TransactionResult struct {
+	Applied    bool          `json:"applied"`
+	Verified   bool          `json:"verified"`
+	RolledBack bool          `json:"rolled_back"`
+	Probes     int           `json:"probes"`
+	Duration   time.Duration `json:"duration"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// TransactionOption is a function argument type for Transaction.
+type TransactionOption func(t *transaction)
+
+// ProbeDeadline sets the time within which the probe has to succeed after
+// the changes have been applied (default: 30s).
+func ProbeDeadline(d time.Duration) TransactionOption {
+	return func(t *transaction) {
+		t.deadline = d
+	}
+}
+
+// ProbeInterval sets the time between two probes (default: 5s).
+func ProbeInterval(d time.Duration) TransactionOption {
+	return func(t *transaction) {
+		t.interval = d
+	}
+}
+
+// RollbackTimeout sets the maximum time for restoring the original
+// attributes, e.g. if the connection to the router has to be
+// re-established (default: 1min).
+func RollbackTimeout(d time.Duration) TransactionOption {
+	return func(t *transaction) {
+		t.rollbackTimeout = d
+	}
+}
+
+type transaction struct {
+	deadline        time.Duration
+	interval        time.Duration
+	rollbackTimeout time.Duration
+	steps           []txStep
+}
+
+// txStep contains the modifications of a route and the original values
+// of the modified attributes.
+type txStep struct {
+	name     string
+	id       string
+	ops      []RouteOp
+	original []RouteOp
+	restored bool
+}
+
+// Transaction applies the changes to one or more registered routes like
+// the safe mode of RouterOS: the affected routes are saved, the changes
+// are applied and the probe is executed until it succeeds. If it doesn't
+// succeed within the deadline (or a change can't be applied), the original
+// attributes are restored. While a transaction is running, further
+// transactions and UpdateRoute fail with ErrTransactionRunning, so that
+// the routes can't be modified behind the transaction's back.
+func (m *Microtik) Transaction(probe Probe, changes []RouteChange, opts ...TransactionOption) (res TransactionResult, err error) {
+
+	if err := m.beginTx(); err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	defer m.endTx()
+
+	// res is a named result, so that the duration is part of the
+	// returned result
+	start := time.Now()
+	defer func() {
+		res.Duration = time.Since(start)
+	}()
+
+	fail := func(err error) (TransactionResult, error) {
+		res.Error = err.Error()
+		return res, err
+	}
+
+	if probe == nil {
+		return fail(fmt.Errorf("transaction requires a probe"))
+	}
+
+	if err := m.checkChanges(changes); err != nil {
+		return fail(err)
+	}
+
+	tx := &transaction{
+		deadline:        time.Second * 30,
+		interval:        time.Second * 5,
+		rollbackTimeout: time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(tx)
+	}
+
+	// take a snapshot of the affected routes
+	for _, c := range changes {
+		name := strings.ToLower(c.Route)
+
+		route, err := m.findRoute(name)
+		if err != nil {
+			return fail(err)
+		}
+
+		id, ok := route[".id"]
+		if !ok {
+			return fail(fmt.Errorf("unable to determine the id of route %s", name))
+		}
+
+		step := txStep{name: name, id: id, ops: c.Ops}
+		for _, op := range c.Ops {
+			if v, ok := route[op.Attribute]; ok {
+				step.original = append(step.original, RouteOp{Attribute: op.Attribute, Value: v})
+			} else {
+				step.original = append(step.original, RouteOp{Attribute: op.Attribute, unset: true})
+			}
+		}
+		tx.steps = append(tx.steps, step)
+	}
+
+	// apply the changes
+	for i, s := range tx.steps {
+		if err := m.applyOps(s.name, s.id, s.ops); err != nil {
+			err = fmt.Errorf("unable to apply changes: %w", err)
+			return fail(m.rollback(tx, tx.steps[:i+1], &res, err))
+		}
+	}
+	res.Applied = true
+
+	// verify the connectivity
+	deadline := time.Now().Add(tx.deadline)
+	for {
+		res.Probes++
+		err := probe()
+		if err == nil {
+			res.Verified = true
+			return res, nil
+		}
+		if time.Now().Add(tx.interval).After(deadline) {
+			err = fmt.Errorf("connectivity check failed after %d probe(s): %v", res.Probes, err)
+			return fail(m.rollback(tx, tx.steps, &res, err))
+		}
+		time.Sleep(tx.interval)
+	}
+}
+
+// CheckTransaction verifies the changes of a transaction without
+// contacting the router: at least one route has to be changed, each route
+// must be registered and changed only once and the modifications must be
+// valid and allowed. It returns ErrTransactionRunning if a transaction is
+// already running.
+func (m *Microtik) CheckTransaction(changes []RouteChange) error {
+
+	m.txMu.Lock()
+	running := m.txRunning
+	m.txMu.Unlock()
+
+	if running {
+		return ErrTransactionRunning
+	}
+
+	return m.checkChanges(changes)
+}
+
+// checkChanges verifies the changes of a transaction.
+func (m *Microtik) checkChanges(changes []RouteChange) error {
+
+	if len(changes) == 0 {
+		return fmt.Errorf("%w: transaction contains no changes", ErrInvalidRouteOp)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, c := range changes {
+		name := strings.ToLower(c.Route)
+
+		if _, ok := m.routes[name]; !ok {
+			return fmt.Errorf("%w %s", ErrUnknownRoute, name)
+		}
+
+		if seen[name] {
+			return fmt.Errorf("%w: route %s changed more than once", ErrInvalidRouteOp, name)
+		}
+		seen[name] = true
+
+		if err := m.checkOps(name, c.Ops); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// beginTx marks the start of a transaction.
+func (m *Microtik) beginTx() error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	if m.txRunning {
+		return ErrTransactionRunning
+	}
+	m.txRunning = true
+
+	return nil
+}
+
+// endTx marks the end of a transaction.
+func (m *Microtik) endTx() {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	m.txRunning = false
+}
+
+// rollback restores the original attributes of the routes in reverse
+// order. Failed restorations are repeated until the rollback timeout
+// expires, since the connection to the router might have to be
+// re-established. The returned error contains the cause of the rollback.
+func (m *Microtik) rollback(tx *transaction, steps []txStep, res *TransactionResult, cause error) error {
+
+	timeout := time.Now().Add(tx.rollbackTimeout)
+
+	for {
+		var err error
+		for i := len(steps) - 1; i >= 0; i-- {
+			if steps[i].restored {
+				continue
+			}
+			if err = m.applyOps(steps[i].name, steps[i].id, steps[i].original); err != nil {
+				log.Printf("unable to restore route %s: %v\n", steps[i].name, err)
+				break
+			}
+			steps[i].restored = true
+		}
+
+		if err == nil {
+			res.RolledBack = true
+			return fmt.Errorf("%w; changes rolled back", cause)
+		}
+
+		if time.Now().After(timeout) {
+			return fmt.Errorf("%w; ROLLBACK FAILED: %v", cause, err)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,132 @@
+package microtik
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"gopkg.in/routeros.v2"
+)
+
+// Supported transports to communicate with the router
+const (
+	// TransportAPI is the binary RouterOS API (services api and api-ssl)
+	TransportAPI = "api"
+	// TransportREST is the REST API of RouterOS v7 (service www-ssl)
+	TransportREST = "rest"
+)
+
+// transport sends commands to the router. Commands are expressed as RouterOS
+// API sentences (e.g. "/ip/route/set", "=.id=*1", "=disabled=false"),
+// independent of the protocol used to transmit them. The reply contains the
+// attributes of each returned item. The command is aborted if the router
+// doesn't reply within the timeout.
+type transport interface {
+	run(timeout time.Duration, sentence ...string) ([]map[string]string, error)
+	close()
+}
+
+// dial establishes a connection to the router through the transport
+// selected in the config.
+func dial(c Config, timeout time.Duration) (transport, error) {
+	switch c.Transport {
+	case TransportREST:
+		return dialREST(c, timeout)
+	case TransportAPI, "":
+		return dialAPI(c, timeout)
+	default:
+		return nil, errors.New("unknown transport " + c.Transport)
+	}
+}
+
+// apiTransport uses the binary RouterOS API. The client runs in
+// asynchronous mode, since RouterOS terminates failed commands with !trap
+// followed by !done. In synchronous mode the trailing !done would be
+// taken as the reply of the next command.
+type apiTransport struct {
+	client *routeros.Client
+	conn   net.Conn
+	// errC reports the error which terminated the asynchronous client
+	errC <-chan error
+}
+
+func dialAPI(c Config, timeout time.Duration) (*apiTransport, error) {
+
+	url := net.JoinHostPort(c.Address, strconv.Itoa(c.Port))
+	conn, err := net.DialTimeout("tcp", url, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if c.TLS {
+		tlsConfig, err := c.tlsConfig()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
+		}
+		conn = tlsConn
+	}
+
+	client, err := routeros.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+
+	if err := client.Login(c.Username, c.Password); err != nil {
+		client.Close()
+		var devErr *routeros.DeviceError
+		if errors.As(err, &devErr) {
+			return nil, fmt.Errorf("%w: %s", ErrAuth, devErr.Sentence.Map["message"])
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	t := &apiTransport{
+		client: client,
+		conn:   conn,
+		errC:   client.Async(),
+	}
+
+	return t, nil
+}
+
+func (t *apiTransport) run(timeout time.Duration, sentence ...string) ([]map[string]string, error) {
+
+	t.conn.SetDeadline(time.Now().Add(timeout))
+	defer t.conn.SetDeadline(time.Time{})
+
+	reply, err := t.client.RunArgs(sentence)
+	if err != nil {
+		return nil, apiError(sentence[0], err)
+	}
+
+	res := make([]map[string]string, 0, len(reply.Re))
+	for _, re := range reply.Re {
+		res = append(res, re.Map)
+	}
+
+	return res, nil
+}
+
+func (t *apiTransport) close() {
+	t.client.Close()
+}
+
+// isDeviceError returns true if the error has been reported by the router
+// itself. In this case the connection is still intact.
+func isDeviceError(err error) bool {
+	return errors.Is(err, ErrRouterTrap)
+}
@@ -0,0 +1,132 @@
+package reset
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dh1tw/infractl/connectivity"
+	"github.com/dh1tw/infractl/mf823"
+	"github.com/dh1tw/infractl/microtik"
+)
+
+// LTERouter is implemented by routers which report the state of their
+// LTE interface.
+type LTERouter interface {
+	HasInterface(name string) (bool, error)
+	InterfaceRunning(name string) (bool, error)
+	DHCPClientStatus(iface string) (string, error)
+}
+
+// RouterChecks returns the checks for the recovery phases based on the
+// LTE interface of the router: the interface must show up again
+// (enumerating), be running (registering) and its DHCP client (if any)
+// must be bound (connecting).
+func RouterChecks(r LTERouter, iface string) map[Phase]Check {
+	return map[Phase]Check{
+		PhaseEnumerating: func() error {
+			ok, err := r.HasInterface(iface)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("interface %s not present", iface)
+			}
+			return nil
+		},
+		PhaseRegistering: func() error {
+			running, err := r.InterfaceRunning(iface)
+			if err != nil {
+				return err
+			}
+			if !running {
+				return fmt.Errorf("interface %s not running", iface)
+			}
+			return nil
+		},
+		PhaseConnecting: func() error {
+			status, err := r.DHCPClientStatus(iface)
+			if err != nil {
+				return err
+			}
+			// no DHCP client configured on the interface
+			if len(status) == 0 {
+				return nil
+			}
+			if status != "bound" {
+				return fmt.Errorf("dhcp client on %s is %s", iface, status)
+			}
+			return nil
+		},
+	}
+}
+
+// MF823Checks returns the checks for the recovery phases based on the
+// status of a ZTE MF823 4G USB modem: the web interface must be reachable
+// (enumerating), network_type must indicate a network (registering) and
+// ppp_status must be ppp_connected (connecting).
+func MF823Checks(address string) map[Phase]Check {
+	return map[Phase]Check{
+		PhaseEnumerating: func() error {
+			_, err := mf823.Status(address, "ppp_status")
+			return err
+		},
+		PhaseRegistering: func() error {
+			res, err := mf823.Status(address, "network_type")
+			if err != nil {
+				return err
+			}
+			nt := fmt.Sprint(res["network_type"])
+			switch nt {
+			case "", "NO_SERVICE", "No Service", "Limited Service", "<nil>":
+				return fmt.Errorf("%s not registered (network type %q)", address, nt)
+			}
+			return nil
+		},
+		PhaseConnecting: func() error {
+			res, err := mf823.Status(address, "ppp_status")
+			if err != nil {
+				return err
+			}
+			status := fmt.Sprint(res["ppp_status"])
+			if status != "ppp_connected" {
+				return fmt.Errorf("ppp status of %s is %s", address, status)
+			}
+			return nil
+		},
+	}
+}
+
+// Pinger is implemented by routers which can ping a host through a
+// particular interface.
+type Pinger interface {
+	Ping(target string, opts microtik.PingOptions) (connectivity.PingResult, error)
+}
+
+// PingCheck returns a check for PhaseVerifying which pings a host on
+// the internet from the router through the LTE interface. Since the pings
+// don't follow the routes, the check only succeeds if the internet is
+// reachable through the modem, even if another uplink is active.
+func PingCheck(r Pinger, iface, host string, timeout time.Duration, samples int) map[Phase]Check {
+
+	opts := microtik.PingOptions{
+		Count:     samples,
+		Interface: iface,
+	}
+	// send all pings within the timeout
+	if samples > 0 {
+		opts.Interval = timeout / time.Duration(samples)
+	}
+
+	return map[Phase]Check{
+		PhaseVerifying: func() error {
+			res, err := r.Ping(host, opts)
+			if err != nil {
+				return err
+			}
+			if res.Failed {
+				return fmt.Errorf("no reply received from %s through %s", host, iface)
+			}
+			return nil
+		},
+	}
+}
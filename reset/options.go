@@ -0,0 +1,83 @@
+package reset
+
+import (
+	"strings"
+	"time"
+)
+
+// EnableRoutes is a functional option which sets the routes which will be
+// enabled before the modem is reset.
+func EnableRoutes(routes ...string) Option {
+	return func(p *Policy) {
+		p.enable = lower(routes)
+	}
+}
+
+// DisableRoutes is a functional option which sets the routes which will be
+// disabled before the modem is reset.
+func DisableRoutes(routes ...string) Option {
+	return func(p *Policy) {
+		p.disable = lower(routes)
+	}
+}
+
+// RestoreRoutes is a functional option which sets the routes whose state
+// (enabled / disabled) will be restored after the reset. If the policy
+// waits for the modem, the routes are only restored once the modem has
+// re-registered.
+func RestoreRoutes(routes ...string) Option {
+	return func(p *Policy) {
+		p.restore = lower(routes)
+	}
+}
+
+// Monitor is a functional option which adds checks for the phases of the
+// recovery after the reset (see RouterChecks, MF823Checks and PingCheck).
+// All checks of a phase must succeed before the next phase is entered.
+func Monitor(checks map[Phase]Check) Option {
+	return func(p *Policy) {
+		for phase, c := range checks {
+			p.checks[phase] = append(p.checks[phase], c)
+		}
+	}
+}
+
+// Wait is a functional option which sets the maximum time to wait for the
+// modem to recover after the power has been restored. Without a waiting
+// time, Run returns right after the reset has been initiated.
+func Wait(timeout time.Duration) Option {
+	return func(p *Policy) {
+		p.wait = timeout
+	}
+}
+
+// PowerOff sets the duration during which the power of the modem is cut.
+// The recovery is monitored afterwards.
+func PowerOff(d time.Duration) Option {
+	return func(p *Policy) {
+		p.powerOff = d
+	}
+}
+
+// Progress sets a function which is called whenever the reset enters a
+// new phase.
+func Progress(fn func(Job)) Option {
+	return func(p *Policy) {
+		p.progress = fn
+	}
+}
+
+// PollInterval sets the interval in which the check is polled.
+func PollInterval(interval time.Duration) Option {
+	return func(p *Policy) {
+		p.interval = interval
+	}
+}
+
+func lower(routes []string) []string {
+	res := make([]string, 0, len(routes))
+	for _, r := range routes {
+		res = append(res, strings.ToLower(r))
+	}
+	return res
+}
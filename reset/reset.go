@@ -0,0 +1,318 @@
+package reset
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dh1tw/infractl/microtik"
+)
+
+// Router is the subset of the microtik.Microtik methods which are needed
+// to reset the 4G modem.
+type Router interface {
+	RouteStatus(name string) (microtik.RouteResult, error)
+	UpdateRoute(name string, ops ...microtik.RouteOp) error
+	Reset4G() error
+}
+
+// Check returns nil if a phase of the recovery of the modem has been
+// completed.
+type Check func() error
+
+// Phase is a step of the reset workflow.
+type Phase string
+
+const (
+	// PhasePreparing means that the routes are enabled / disabled
+	// before the reset.
+	PhasePreparing Phase = "preparing"
+	// PhasePoweringOff means that the power of the USB port is cut.
+	PhasePoweringOff Phase = "powering_off"
+	// PhaseEnumerating means that the modem is waited for to show up on
+	// the USB bus again.
+	PhaseEnumerating Phase = "enumerating_usb"
+	// PhaseRegistering means that the modem is waited for to register
+	// to the LTE network.
+	PhaseRegistering Phase = "registering_lte"
+	// PhaseConnecting means that the PPP / DHCP connection is waited for.
+	PhaseConnecting Phase = "connecting"
+	// PhaseVerifying means that the internet is waited for to be
+	// reachable through the modem.
+	PhaseVerifying Phase = "verifying_internet"
+	// PhaseRestoring means that the routes are restored.
+	PhaseRestoring Phase = "restoring_routes"
+	// PhaseDone means that the reset completed successfully.
+	PhaseDone Phase = "done"
+	// PhaseFailed means that the reset failed or timed out.
+	PhaseFailed Phase = "failed"
+)
+
+// recoveryPhases are the phases which are monitored after the reset,
+// in their chronological order.
+var recoveryPhases = []Phase{PhaseEnumerating, PhaseRegistering, PhaseConnecting, PhaseVerifying}
+
+// PhaseStatus records when a phase of the workflow has been entered.
+type PhaseStatus struct {
+	Phase   Phase     `json:"phase"`
+	Started time.Time `json:"started"`
+}
+
+// Job describes the progress of a reset.
+type Job struct {
+	ID       int           `json:"id"`
+	Phase    Phase         `json:"phase"`
+	Phases   []PhaseStatus `json:"phases"`
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Done     bool          `json:"done"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func (j Job) copy() Job {
+	j.Phases = append([]PhaseStatus(nil), j.Phases...)
+	return j
+}
+
+// Policy resets the 4G modem connected to the router. Before the reset,
+// routes can be enabled or disabled, e.g. in order to make sure that
+// another uplink is available while the modem is down. After the reset,
+// the policy optionally waits until the modem has recovered and restores
+// the previous state of the selected routes. The recovery is tracked in
+// phases, each of which is completed when all its checks succeed.
+// Only one reset is executed at a time.
+type Policy struct {
+	sync.Mutex
+	router   Router
+	enable   []string
+	disable  []string
+	restore  []string
+	checks   map[Phase][]Check
+	powerOff time.Duration
+	wait     time.Duration
+	interval time.Duration
+	progress func(Job)
+	job      Job
+	running  bool
+}
+
+// Option is the type used for functional options
+type Option func(*Policy)
+
+// New returns a Policy which resets the 4G modem through the router. Without
+// options, the modem is reset without touching any routes.
+func New(r Router, opts ...Option) *Policy {
+
+	p := &Policy{
+		router:   r,
+		checks:   make(map[Phase][]Check),
+		powerOff: time.Second * 5,
+		interval: time.Second * 5,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Waits returns true if the policy waits for the modem to recover.
+func (p *Policy) Waits() bool {
+	return p.wait > 0 && len(p.checks) > 0
+}
+
+// Status returns the current (or last) reset job.
+func (p *Policy) Status() Job {
+	p.Lock()
+	defer p.Unlock()
+	return p.job.copy()
+}
+
+// Run executes the policy and returns the finished job. It returns an
+// error if any of the steps fails or if the modem has not recovered in
+// time. In the latter case, the routes are not restored.
+func (p *Policy) Run() (Job, error) {
+	if err := p.begin(); err != nil {
+		return Job{}, err
+	}
+	return p.execute()
+}
+
+// Start executes the policy in the background and returns the started
+// job. The progress can be retrieved with Status.
+func (p *Policy) Start() (Job, error) {
+	if err := p.begin(); err != nil {
+		return Job{}, err
+	}
+	job := p.Status()
+	go func() {
+		if _, err := p.execute(); err != nil {
+			log.Println("reset 4G:", err)
+		}
+	}()
+	return job, nil
+}
+
+// begin creates a new job unless a reset is already in progress.
+func (p *Policy) begin() error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.running {
+		return fmt.Errorf("4G modem reset already in progress (job %d)", p.job.ID)
+	}
+	p.running = true
+
+	p.job = Job{
+		ID:      p.job.ID + 1,
+		Started: time.Now(),
+	}
+
+	return nil
+}
+
+func (p *Policy) execute() (Job, error) {
+
+	err := p.workflow()
+
+	p.Lock()
+	p.running = false
+	p.job.Done = true
+	p.job.Finished = time.Now()
+	if err != nil {
+		p.job.Error = err.Error()
+		p.job.Phases = append(p.job.Phases, PhaseStatus{PhaseFailed, p.job.Finished})
+		p.job.Phase = PhaseFailed
+	} else {
+		p.job.Success = true
+		p.job.Phases = append(p.job.Phases, PhaseStatus{PhaseDone, p.job.Finished})
+		p.job.Phase = PhaseDone
+	}
+	job := p.job.copy()
+	p.Unlock()
+
+	if p.progress != nil {
+		p.progress(job)
+	}
+
+	return job, err
+}
+
+// enter records the transition into a new phase.
+func (p *Policy) enter(phase Phase) {
+	p.Lock()
+	p.job.Phase = phase
+	p.job.Phases = append(p.job.Phases, PhaseStatus{phase, time.Now()})
+	job := p.job.copy()
+	p.Unlock()
+
+	if p.progress != nil {
+		p.progress(job)
+	}
+}
+
+func (p *Policy) workflow() error {
+
+	p.enter(PhasePreparing)
+
+	// save the state of the routes which will be restored afterwards
+	saved := make(map[string]bool)
+	for _, route := range p.restore {
+		res, err := p.router.RouteStatus(route)
+		if err != nil {
+			return fmt.Errorf("unable to save the state of route %s: %w", route, err)
+		}
+		saved[route] = res["disabled"]
+	}
+
+	for _, route := range p.enable {
+		if err := p.router.UpdateRoute(route, microtik.Enable()); err != nil {
+			return fmt.Errorf("unable to enable route %s before the reset: %w", route, err)
+		}
+	}
+
+	for _, route := range p.disable {
+		if err := p.router.UpdateRoute(route, microtik.Disable()); err != nil {
+			return fmt.Errorf("unable to disable route %s before the reset: %w", route, err)
+		}
+	}
+
+	p.enter(PhasePoweringOff)
+
+	if err := p.router.Reset4G(); err != nil {
+		return err
+	}
+
+	if p.Waits() {
+		time.Sleep(p.powerOff)
+		if err := p.waitForRecovery(); err != nil {
+			return fmt.Errorf("%w; routes not restored", err)
+		}
+	}
+
+	if len(p.restore) > 0 {
+		p.enter(PhaseRestoring)
+	}
+
+	return p.restoreRoutes(saved)
+}
+
+// waitForRecovery polls the checks of each recovery phase until they
+// succeed or the waiting time has expired.
+func (p *Policy) waitForRecovery() error {
+
+	deadline := time.Now().Add(p.wait)
+
+	for _, phase := range recoveryPhases {
+		checks, ok := p.checks[phase]
+		if !ok {
+			continue
+		}
+
+		p.enter(phase)
+
+		for {
+			err := all(checks)
+			if err == nil {
+				break
+			}
+			if time.Now().Add(p.interval).After(deadline) {
+				return fmt.Errorf("4G modem not recovered within %v (%s): %v",
+					p.powerOff+p.wait, phase, err)
+			}
+			time.Sleep(p.interval)
+		}
+	}
+
+	return nil
+}
+
+// all returns the first error of the checks.
+func all(checks []Check) error {
+	for _, c := range checks {
+		if err := c(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreRoutes sets the routes back to the saved state.
+func (p *Policy) restoreRoutes(saved map[string]bool) error {
+
+	for _, route := range p.restore {
+		op := microtik.Enable()
+		if saved[route] {
+			op = microtik.Disable()
+		}
+		if err := p.router.UpdateRoute(route, op); err != nil {
+			return fmt.Errorf("unable to restore route %s after the reset: %w", route, err)
+		}
+		log.Printf("reset: route %s restored (disabled=%v)\n", route, saved[route])
+	}
+
+	return nil
+}